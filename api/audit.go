@@ -0,0 +1,166 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// auditEventResponse converts an audit event into its JSON representation
+func auditEventResponse(event *db.AuditEvent) map[string]interface{} {
+	item := map[string]interface{}{
+		"id":             event.ID,
+		"request_id":     event.RequestID,
+		"actor":          event.Actor,
+		"remote_ip":      event.RemoteIP,
+		"method":         event.Method,
+		"path":           event.Path,
+		"namespace":      event.Namespace,
+		"key":            nil,
+		"old_value_hash": nil,
+		"new_value_hash": nil,
+		"status":         event.Status,
+		"result":         event.Result,
+		"created_at":     event.CreatedAt,
+	}
+
+	if event.Key != "" {
+		item["key"] = event.Key
+	}
+	if event.OldValueHash != "" {
+		item["old_value_hash"] = event.OldValueHash
+	}
+	if event.NewValueHash != "" {
+		item["new_value_hash"] = event.NewValueHash
+	}
+
+	return item
+}
+
+// auditFilter parses the filters and pagination of an audit listing request
+func auditFilter(w http.ResponseWriter, r *http.Request) (db.AuditFilter, bool) {
+	query := r.URL.Query()
+
+	filter := db.AuditFilter{
+		Actor:     query.Get("actor"),
+		RequestID: query.Get("request_id"),
+		Method:    strings.ToUpper(query.Get("method")),
+		Namespace: query.Get("namespace"),
+		Key:       query.Get("key"),
+		Result:    query.Get("result"),
+		Limit:     defaultListLimit,
+	}
+
+	if filter.Result != "" && filter.Result != db.AuditResultSuccess && filter.Result != db.AuditResultFailure {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid result parameter, expected success or failure",
+		})
+		return filter, false
+	}
+
+	for _, bound := range []struct {
+		name   string
+		target **time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		raw := query.Get(bound.name)
+		if raw == "" {
+			continue
+		}
+
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid " + bound.name + " parameter, expected an RFC 3339 time",
+			})
+			return filter, false
+		}
+		*bound.target = &at
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > maxListLimit {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid limit parameter",
+			})
+			return filter, false
+		}
+		filter.Limit = value
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		values, err := service.DecodeCursor(raw, 1)
+		if err == nil {
+			filter.BeforeID, err = strconv.ParseInt(values[0], 10, 64)
+		}
+		if err != nil || filter.BeforeID < 1 {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid cursor parameter",
+			})
+			return filter, false
+		}
+	}
+
+	return filter, true
+}
+
+// ListAuditEventsAction handles GET requests to list audit events, newest
+// first. Events can be filtered by actor, request_id, method, namespace, key,
+// result and a since/until time range, and paginated with limit and the
+// next_cursor of the previous page.
+func ListAuditEventsAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("List audit events endpoint called")
+
+	database := db.GetDB()
+	if database == nil {
+		log.Error().Msg("Database not initialized")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Database not initialized",
+		})
+		return
+	}
+
+	filter, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch one extra event to know whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+
+	events, err := db.NewAuditRepository(database).Find(filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list audit events")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list audit events",
+		})
+		return
+	}
+
+	var next interface{}
+	if len(events) > limit {
+		events = events[:limit]
+		next = service.EncodeCursor(strconv.FormatInt(events[limit-1].ID, 10))
+	}
+
+	items := make([]map[string]interface{}, 0, len(events))
+	for _, event := range events {
+		items = append(items, auditEventResponse(event))
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"events":      items,
+		"next_cursor": next,
+	})
+}
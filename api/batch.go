@@ -0,0 +1,186 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxBatchOperations is the maximum number of operations in a batch
+const maxBatchOperations = 100
+
+// BatchOperationRequest represents a single operation of a batch request
+type BatchOperationRequest struct {
+	// Op is one of set, delete or check
+	Op  string `json:"op"`
+	Key string `json:"key"`
+	// Value and TTL are used by set operations
+	Value json.RawMessage `json:"value"`
+	TTL   int64           `json:"ttl"`
+	// ExpectedVersion, if set, requires the option to be at this version
+	ExpectedVersion int64 `json:"expected_version"`
+	// Exists, if set, requires the option to exist or not
+	Exists *bool `json:"exists"`
+}
+
+// BatchRequest represents the request body for applying operations atomically
+type BatchRequest struct {
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// batchOperation validates an operation of a batch request
+func batchOperation(req BatchOperationRequest) (db.BatchOperation, error) {
+	operation := db.BatchOperation{
+		Op:              req.Op,
+		Key:             req.Key,
+		ExpectedVersion: req.ExpectedVersion,
+		Exists:          req.Exists,
+	}
+
+	if req.Op != db.BatchOpSet && req.Op != db.BatchOpDelete && req.Op != db.BatchOpCheck {
+		return operation, fmt.Errorf("unsupported operation %q", req.Op)
+	}
+
+	if !service.IsValidKey(req.Key) {
+		return operation, fmt.Errorf("invalid option key")
+	}
+
+	if req.ExpectedVersion < 0 {
+		return operation, fmt.Errorf("expected_version must not be negative")
+	}
+
+	if req.Op != db.BatchOpSet {
+		return operation, nil
+	}
+
+	value, err := service.NormalizeJSON(req.Value)
+	if err != nil {
+		return operation, fmt.Errorf("value must be a JSON document")
+	}
+	operation.Value = value
+
+	if req.TTL < 0 {
+		return operation, fmt.Errorf("ttl must not be negative")
+	}
+	if req.TTL > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.TTL) * time.Second)
+		operation.ExpiresAt = &t
+	}
+
+	return operation, nil
+}
+
+// BatchOptionsAction handles POST requests to apply set, delete and check
+// operations atomically. If any precondition fails nothing is applied.
+func BatchOptionsAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("Batch options endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	var req BatchRequest
+	if err := service.DecodeJSON(r, &req); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("A batch must hold between 1 and %d operations", maxBatchOperations),
+		})
+		return
+	}
+
+	operations := make([]db.BatchOperation, 0, len(req.Operations))
+	for i, item := range req.Operations {
+		operation, err := batchOperation(item)
+		if err != nil {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": fmt.Sprintf("Invalid operation %d: %s", i, err),
+				"index": i,
+			})
+			return
+		}
+		operations = append(operations, operation)
+	}
+
+	results, err := repo.Batch(operations)
+
+	var batchErr *db.BatchError
+	var violation *db.SchemaViolationError
+
+	switch {
+	case err == nil:
+	case errors.As(err, &violation):
+		errors.As(err, &batchErr)
+		service.WriteJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":      "Value does not conform to schema",
+			"index":      batchErr.Index,
+			"key":        violation.Key,
+			"prefix":     violation.Schema.Prefix,
+			"violations": violation.Violations,
+		})
+		return
+	case errors.Is(err, db.ErrPreconditionFailed):
+		errors.As(err, &batchErr)
+		log.Info().Err(err).Msg("Batch precondition failed")
+		service.WriteJSON(w, http.StatusPreconditionFailed, map[string]interface{}{
+			"error": batchErr.Err.Error(),
+			"index": batchErr.Index,
+			"key":   batchErr.Key,
+		})
+		return
+	default:
+		log.Error().Err(err).Msg("Failed to apply batch")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to apply batch",
+		})
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		item := map[string]interface{}{
+			"op":  result.Op,
+			"key": result.Key,
+		}
+
+		switch result.Op {
+		case db.BatchOpDelete:
+			item["deleted"] = result.Deleted
+		default:
+			item["option"] = nil
+			if result.Option != nil {
+				item["option"] = optionResponse(result.Option, false)
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	log.Info().
+		Str("namespace", repo.Namespace()).
+		Int("operations", len(operations)).
+		Msg("Batch applied successfully")
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace": repo.Namespace(),
+		"results":   items,
+	})
+}
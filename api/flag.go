@@ -0,0 +1,275 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// EvaluateFlagsRequest represents the request body for evaluating flags
+type EvaluateFlagsRequest struct {
+	// Context is what the flags are evaluated against
+	Context service.FlagContext `json:"context"`
+	// Flags are the names of the flags to evaluate, all flags if empty
+	Flags []string `json:"flags"`
+}
+
+// flagResponse converts an option holding a flag into its JSON representation
+func flagResponse(option *db.Option) map[string]interface{} {
+	return map[string]interface{}{
+		"namespace":  option.Namespace,
+		"name":       db.FlagName(option.Key),
+		"flag":       json.RawMessage(option.Value),
+		"version":    option.Version,
+		"created_at": option.CreatedAt,
+		"updated_at": option.UpdatedAt,
+	}
+}
+
+// flagName extracts and validates the flag name from the URL
+func flagName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := chi.URLParam(r, "flag")
+
+	if !service.IsValidKey(name) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid flag name",
+		})
+		return "", false
+	}
+
+	return name, true
+}
+
+// evaluateFlag resolves the variant of the flag held by an option for a context
+func evaluateFlag(option *db.Option, context service.FlagContext) service.FlagResult {
+	flag, violations := service.ParseFlag(option.Value)
+	if len(violations) > 0 {
+		log.Warn().Str("key", option.Key).Msg("Skipping invalid flag definition")
+		return service.FlagResult{Reason: service.FlagReasonError, Value: json.RawMessage("null")}
+	}
+
+	return flag.Evaluate(option.Key, context)
+}
+
+// ListFlagsAction handles GET requests to list the flags of a namespace
+func ListFlagsAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("List flags endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	options, err := repo.Flags()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list flags")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list flags",
+		})
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(options))
+	for _, option := range options {
+		items = append(items, flagResponse(option))
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace": repo.Namespace(),
+		"flags":     items,
+	})
+}
+
+// GetFlagAction handles GET requests to retrieve a flag
+func GetFlagAction(w http.ResponseWriter, r *http.Request) {
+	name, ok := flagName(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("flag", name).Msg("Get flag endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	option, err := repo.Get(db.FlagKey(name))
+	if err != nil {
+		log.Error().Err(err).Str("flag", name).Msg("Failed to get flag")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve flag",
+		})
+		return
+	}
+
+	if option == nil {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Flag not found",
+		})
+		return
+	}
+
+	w.Header().Set("ETag", service.ETag(option.Version))
+	service.WriteJSON(w, http.StatusOK, flagResponse(option))
+}
+
+// PutFlagAction handles PUT requests to create or update a flag. The request
+// body is the flag definition.
+func PutFlagAction(w http.ResponseWriter, r *http.Request) {
+	name, ok := flagName(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("flag", name).Msg("Put flag endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	var definition json.RawMessage
+	if err := service.DecodeJSON(r, &definition); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	value, err := service.NormalizeJSON(definition)
+	if err != nil {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Flag must be a JSON document",
+		})
+		return
+	}
+
+	option, status, ok := saveOption(w, r, repo, db.FlagKey(name), value, nil)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("ETag", service.ETag(option.Version))
+	service.WriteJSON(w, status, flagResponse(option))
+}
+
+// DeleteFlagAction handles DELETE requests to move a flag to the trash
+func DeleteFlagAction(w http.ResponseWriter, r *http.Request) {
+	name, ok := flagName(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("flag", name).Msg("Delete flag endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := repo.Get(db.FlagKey(name))
+	if err != nil {
+		log.Error().Err(err).Str("flag", name).Msg("Failed to check existing flag")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to check flag",
+		})
+		return
+	}
+
+	if existing == nil {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Flag not found",
+		})
+		return
+	}
+
+	if err := repo.Delete(db.FlagKey(name)); err != nil {
+		log.Error().Err(err).Str("flag", name).Msg("Failed to delete flag")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to delete flag",
+		})
+		return
+	}
+
+	log.Info().Str("flag", name).Msg("Flag deleted successfully")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EvaluateFlagsAction handles POST requests to resolve the variants of flags
+// for a context. Flags that do not exist resolve with the not_found reason.
+func EvaluateFlagsAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("Evaluate flags endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	var req EvaluateFlagsRequest
+	if err := service.DecodeJSON(r, &req); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	results := make(map[string]service.FlagResult)
+
+	if len(req.Flags) == 0 {
+		options, err := repo.Flags()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list flags")
+			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to evaluate flags",
+			})
+			return
+		}
+
+		for _, option := range options {
+			results[db.FlagName(option.Key)] = evaluateFlag(option, req.Context)
+		}
+	}
+
+	for _, name := range req.Flags {
+		if !service.IsValidKey(name) {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid flag name",
+				"flag":  name,
+			})
+			return
+		}
+
+		option, err := repo.Get(db.FlagKey(name))
+		if err != nil {
+			log.Error().Err(err).Str("flag", name).Msg("Failed to get flag")
+			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to evaluate flags",
+			})
+			return
+		}
+
+		if option == nil {
+			results[name] = service.FlagResult{Reason: service.FlagReasonNotFound, Value: json.RawMessage("null")}
+			continue
+		}
+
+		results[name] = evaluateFlag(option, req.Context)
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace": repo.Namespace(),
+		"flags":     results,
+	})
+}
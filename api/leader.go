@@ -0,0 +1,46 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LeaderAction handles GET requests for the leader election state of the
+// replica serving the request
+func LeaderAction(w http.ResponseWriter, _ *http.Request) {
+	log.Debug().Msg("Leader endpoint called")
+
+	elector := db.GetElector()
+	if elector == nil {
+		service.WriteJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error": "Leader election not started",
+		})
+		return
+	}
+
+	status := elector.Status()
+
+	response := map[string]interface{}{
+		"identity": status.Identity,
+		"leader":   status.Leader,
+		"since":    nil,
+		"holder":   nil,
+	}
+
+	if status.Leader {
+		response["since"] = status.Since
+	}
+	if status.Holder != nil {
+		response["holder"] = lockResponse(status.Holder)
+	}
+
+	service.WriteJSON(w, http.StatusOK, response)
+}
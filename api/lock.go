@@ -0,0 +1,366 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// maxLockTTL is the longest lease a lock can be acquired or renewed for
+const maxLockTTL = 24 * time.Hour
+
+// AcquireLockRequest represents the request body for acquiring a lock
+type AcquireLockRequest struct {
+	// Owner identifies the holder, for example a pod name
+	Owner string `json:"owner"`
+	// TTL is the lifetime of the lease in seconds
+	TTL int64 `json:"ttl"`
+}
+
+// RenewLockRequest represents the request body for renewing a lock
+type RenewLockRequest struct {
+	// Token is the token returned when the lock was acquired
+	Token string `json:"token"`
+	// TTL is the new lifetime of the lease in seconds from now
+	TTL int64 `json:"ttl"`
+}
+
+// ReleaseLockRequest represents the request body for releasing a lock
+type ReleaseLockRequest struct {
+	// Token is the token returned when the lock was acquired
+	Token string `json:"token"`
+}
+
+// ValidateLockRequest represents the request body for validating a fencing token
+type ValidateLockRequest struct {
+	Fence int64 `json:"fence"`
+}
+
+// lockResponse converts a lease into its JSON representation. The token is
+// only included for the holder.
+func lockResponse(lease *db.Lease) map[string]interface{} {
+	response := map[string]interface{}{
+		"name":        lease.Name,
+		"owner":       lease.Owner,
+		"fence":       lease.Fence,
+		"held":        lease.Held(),
+		"acquired_at": lease.AcquiredAt,
+		"expires_at":  lease.ExpiresAt,
+	}
+
+	if lease.Token != "" {
+		response["token"] = lease.Token
+	}
+	return response
+}
+
+// lockName extracts and validates the lock name from the URL
+func lockName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := chi.URLParam(r, "name")
+
+	if !service.IsValidKey(name) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid lock name",
+		})
+		return "", false
+	}
+
+	return name, true
+}
+
+// lockTTL validates the lease lifetime in seconds of a request
+func lockTTL(w http.ResponseWriter, seconds int64) (time.Duration, bool) {
+	ttl := time.Duration(seconds) * time.Second
+
+	if seconds <= 0 || ttl > maxLockTTL {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "TTL must be between 1 and 86400 seconds",
+		})
+		return 0, false
+	}
+
+	return ttl, true
+}
+
+// leaseRepository returns a lease repository, or writes an error if the
+// database is not ready
+func leaseRepository(w http.ResponseWriter) (*db.LeaseRepository, bool) {
+	database := db.GetDB()
+	if database == nil {
+		log.Error().Msg("Database not initialized")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Database not initialized",
+		})
+		return nil, false
+	}
+
+	return db.NewLeaseRepository(database), true
+}
+
+// ListLocksAction handles GET requests to list the held locks
+func ListLocksAction(w http.ResponseWriter, _ *http.Request) {
+	log.Debug().Msg("List locks endpoint called")
+
+	repo, ok := leaseRepository(w)
+	if !ok {
+		return
+	}
+
+	leases, err := repo.List()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list locks")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list locks",
+		})
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(leases))
+	for _, lease := range leases {
+		items = append(items, lockResponse(lease))
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"locks": items,
+	})
+}
+
+// GetLockAction handles GET requests to retrieve the state of a lock
+func GetLockAction(w http.ResponseWriter, r *http.Request) {
+	name, ok := lockName(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("lock", name).Msg("Get lock endpoint called")
+
+	repo, ok := leaseRepository(w)
+	if !ok {
+		return
+	}
+
+	lease, err := repo.Get(name)
+	if err != nil {
+		log.Error().Err(err).Str("lock", name).Msg("Failed to get lock")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve lock",
+		})
+		return
+	}
+
+	if lease == nil {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Lock not found",
+		})
+		return
+	}
+
+	service.WriteJSON(w, http.StatusOK, lockResponse(lease))
+}
+
+// AcquireLockAction handles POST requests to acquire a lock. It writes 409
+// Conflict with the current holder if the lock is held.
+func AcquireLockAction(w http.ResponseWriter, r *http.Request) {
+	name, ok := lockName(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("lock", name).Msg("Acquire lock endpoint called")
+
+	repo, ok := leaseRepository(w)
+	if !ok {
+		return
+	}
+
+	var req AcquireLockRequest
+	if err := service.DecodeJSON(r, &req); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if req.Owner == "" || len(req.Owner) > 255 {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Owner is required and must not exceed 255 characters",
+		})
+		return
+	}
+
+	if strings.HasPrefix(name, db.ReservedLeasePrefix) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Lock names starting with " + db.ReservedLeasePrefix + " are reserved",
+		})
+		return
+	}
+
+	ttl, ok := lockTTL(w, req.TTL)
+	if !ok {
+		return
+	}
+
+	lease, acquired, err := repo.Acquire(name, req.Owner, ttl)
+	if err != nil {
+		log.Error().Err(err).Str("lock", name).Msg("Failed to acquire lock")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to acquire lock",
+		})
+		return
+	}
+
+	if !acquired {
+		response := map[string]interface{}{
+			"error": "Lock is held",
+		}
+		if lease != nil {
+			response["lock"] = lockResponse(lease)
+		}
+
+		service.WriteJSON(w, http.StatusConflict, response)
+		return
+	}
+
+	log.Info().Str("lock", name).Str("owner", lease.Owner).Int64("fence", lease.Fence).Msg("Lock acquired")
+	service.WriteJSON(w, http.StatusOK, lockResponse(lease))
+}
+
+// RenewLockAction handles POST requests to extend the lease of a held lock.
+// It writes 409 Conflict if the token does not hold the lock anymore.
+func RenewLockAction(w http.ResponseWriter, r *http.Request) {
+	name, ok := lockName(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("lock", name).Msg("Renew lock endpoint called")
+
+	repo, ok := leaseRepository(w)
+	if !ok {
+		return
+	}
+
+	var req RenewLockRequest
+	if err := service.DecodeJSON(r, &req); err != nil || req.Token == "" {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	ttl, ok := lockTTL(w, req.TTL)
+	if !ok {
+		return
+	}
+
+	lease, renewed, err := repo.Renew(name, req.Token, ttl)
+	if err != nil {
+		log.Error().Err(err).Str("lock", name).Msg("Failed to renew lock")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to renew lock",
+		})
+		return
+	}
+
+	if !renewed {
+		service.WriteJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": "Lock is not held with this token",
+		})
+		return
+	}
+
+	service.WriteJSON(w, http.StatusOK, lockResponse(lease))
+}
+
+// ReleaseLockAction handles POST requests to release a held lock. It writes
+// 409 Conflict if the token does not hold the lock anymore.
+func ReleaseLockAction(w http.ResponseWriter, r *http.Request) {
+	name, ok := lockName(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("lock", name).Msg("Release lock endpoint called")
+
+	repo, ok := leaseRepository(w)
+	if !ok {
+		return
+	}
+
+	var req ReleaseLockRequest
+	if err := service.DecodeJSON(r, &req); err != nil || req.Token == "" {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	released, err := repo.Release(name, req.Token)
+	if err != nil {
+		log.Error().Err(err).Str("lock", name).Msg("Failed to release lock")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to release lock",
+		})
+		return
+	}
+
+	if !released {
+		service.WriteJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": "Lock is not held with this token",
+		})
+		return
+	}
+
+	log.Info().Str("lock", name).Msg("Lock released")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ValidateLockAction handles POST requests to check whether a fencing token
+// is the one of the current holder, so stale holders can be rejected
+func ValidateLockAction(w http.ResponseWriter, r *http.Request) {
+	name, ok := lockName(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("lock", name).Msg("Validate lock endpoint called")
+
+	repo, ok := leaseRepository(w)
+	if !ok {
+		return
+	}
+
+	var req ValidateLockRequest
+	if err := service.DecodeJSON(r, &req); err != nil {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	valid, err := repo.Validate(name, req.Fence)
+	if err != nil {
+		log.Error().Err(err).Str("lock", name).Msg("Failed to validate lock")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to validate lock",
+		})
+		return
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"name":  name,
+		"fence": req.Fence,
+		"valid": valid,
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ListNamespacesAction handles GET requests to list namespaces holding options
+func ListNamespacesAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("List namespaces endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	namespaces, err := repo.ListNamespaces()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list namespaces")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list namespaces",
+		})
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		items = append(items, map[string]interface{}{
+			"name":    namespace.Name,
+			"options": namespace.Options,
+		})
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"namespaces": items,
+	})
+}
+
+// DeleteNamespaceAction handles DELETE requests to remove every option of a namespace
+func DeleteNamespaceAction(w http.ResponseWriter, r *http.Request) {
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("namespace", repo.Namespace()).Msg("Delete namespace endpoint called")
+
+	deleted, err := repo.DeleteNamespace()
+	if err != nil {
+		log.Error().Err(err).Str("namespace", repo.Namespace()).Msg("Failed to delete namespace")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to delete namespace",
+		})
+		return
+	}
+
+	if deleted == 0 {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Namespace not found",
+		})
+		return
+	}
+
+	log.Info().
+		Str("namespace", repo.Namespace()).
+		Int("count", deleted).
+		Msg("Namespace deleted successfully")
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace": repo.Namespace(),
+		"deleted":   deleted,
+	})
+}
@@ -0,0 +1,598 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/middleware"
+	"github.com/clivern/zewi/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// PutOptionRequest represents the request body for creating or updating an option
+type PutOptionRequest struct {
+	// Value is any JSON document
+	Value json.RawMessage `json:"value"`
+	// TTL is the lifetime of the option in seconds, zero means it never expires
+	TTL int64 `json:"ttl"`
+	// Secret, if set, marks the option as secret so its value is encrypted
+	// at rest and masked unless revealed. Otherwise the option stays as is.
+	Secret *bool `json:"secret"`
+}
+
+// optionResponse converts an option into its JSON representation. The value
+// of a secret option is masked unless revealed.
+func optionResponse(option *db.Option, reveal bool) map[string]interface{} {
+	return map[string]interface{}{
+		"namespace":  option.Namespace,
+		"key":        option.Key,
+		"value":      json.RawMessage(option.DisplayValue(reveal)),
+		"secret":     option.Secret,
+		"version":    option.Version,
+		"expires_at": option.ExpiresAt,
+		"created_at": option.CreatedAt,
+		"updated_at": option.UpdatedAt,
+	}
+}
+
+// revealSecrets reports whether the request asks for secret values with
+// reveal=true
+func revealSecrets(r *http.Request) bool {
+	return r.URL.Query().Get("reveal") == "true"
+}
+
+// encryptionDisabled writes an error if err is caused by writing a secret
+// option while encryption is not configured
+func encryptionDisabled(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, db.ErrEncryptionDisabled) {
+		return false
+	}
+
+	service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error": "Encryption is not configured, secret options can not be stored",
+	})
+	return true
+}
+
+// optionKey extracts and validates the option key from the URL
+func optionKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := chi.URLParam(r, "key")
+
+	if !service.IsValidKey(key) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid option key",
+		})
+		return "", false
+	}
+
+	return key, true
+}
+
+// optionRepository returns an option repository scoped to the namespace in the
+// URL, or the default namespace. It writes an error if the namespace is invalid
+// or the database is not ready.
+func optionRepository(w http.ResponseWriter, r *http.Request) (*db.OptionRepository, bool) {
+	namespace := chi.URLParam(r, "namespace")
+	if namespace == "" {
+		namespace = db.DefaultNamespace
+	}
+
+	if !service.IsValidKey(namespace) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid namespace",
+		})
+		return nil, false
+	}
+
+	database := db.GetDB()
+	if database == nil {
+		log.Error().Msg("Database not initialized")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Database not initialized",
+		})
+		return nil, false
+	}
+
+	repo := db.NewOptionRepository(database).WithNamespace(namespace)
+	return repo.WithAuditTrail(middleware.GetAuditTrail(r.Context())), true
+}
+
+// saveOption creates or updates an option while honoring the If-Match header.
+// Values not conforming to the schema of the key are rejected. Without If-Match
+// the write is an atomic upsert. With If-Match the write only happens if the
+// stored version still matches, otherwise 412 Precondition Failed is returned.
+func saveOption(w http.ResponseWriter, r *http.Request, repo *db.OptionRepository, key, value string, expiresAt *time.Time) (*db.Option, int, bool) {
+	if !validateOptionValue(w, repo, key, value) {
+		return nil, 0, false
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+
+	if ifMatch == "" {
+		option, created, err := repo.Upsert(key, value, expiresAt)
+		if encryptionDisabled(w, err) {
+			return nil, 0, false
+		}
+		if err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to save option")
+			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to save option",
+			})
+			return nil, 0, false
+		}
+
+		if created {
+			log.Info().Str("key", key).Msg("Option created successfully")
+			return option, http.StatusCreated, true
+		}
+
+		log.Info().Str("key", key).Msg("Option updated successfully")
+		return option, http.StatusOK, true
+	}
+
+	// If-Match: * only requires the option to exist
+	var expected int64
+	if ifMatch != "*" {
+		version, ok := service.ParseETag(ifMatch)
+		if !ok {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid If-Match header",
+			})
+			return nil, 0, false
+		}
+		expected = version
+	}
+
+	option, err := repo.CompareAndSwap(key, value, expiresAt, expected)
+	if encryptionDisabled(w, err) {
+		return nil, 0, false
+	}
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to update option")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to update option",
+		})
+		return nil, 0, false
+	}
+
+	if option == nil && expected == 0 {
+		service.WriteJSON(w, http.StatusPreconditionFailed, map[string]interface{}{
+			"error": "Option does not exist",
+		})
+		return nil, 0, false
+	}
+
+	if option == nil {
+		log.Info().
+			Str("key", key).
+			Int64("expected_version", expected).
+			Msg("Option version mismatch")
+
+		service.WriteJSON(w, http.StatusPreconditionFailed, map[string]interface{}{
+			"error": "Option was modified by another request",
+		})
+		return nil, 0, false
+	}
+
+	log.Info().Str("key", key).Msg("Option updated successfully")
+	return option, http.StatusOK, true
+}
+
+// listOptions parses the filters, sort order and cursor of a listing request
+func listOptions(w http.ResponseWriter, r *http.Request) (db.ListOptions, bool) {
+	query := r.URL.Query()
+
+	opts := db.ListOptions{
+		Prefix: query.Get("prefix"),
+		Query:  query.Get("q"),
+		Sort:   query.Get("sort"),
+		Limit:  defaultListLimit,
+	}
+
+	if opts.Sort == "" {
+		opts.Sort = db.SortByKey
+	}
+	if !db.IsValidSort(opts.Sort) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid sort parameter, expected key, created_at or updated_at",
+		})
+		return opts, false
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid order parameter, expected asc or desc",
+		})
+		return opts, false
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > maxListLimit {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid limit parameter",
+			})
+			return opts, false
+		}
+		opts.Limit = value
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, ok := decodeListCursor(raw, opts.Sort)
+		if !ok {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid cursor parameter",
+			})
+			return opts, false
+		}
+		opts.After = cursor
+	}
+
+	return opts, true
+}
+
+// encodeListCursor encodes the position of a listing page along with its sort
+// field so a cursor can not be reused with another sort order
+func encodeListCursor(cursor *db.ListCursor, sort string) string {
+	return service.EncodeCursor(sort, cursor.Time.UTC().Format(time.RFC3339Nano), cursor.Key)
+}
+
+// decodeListCursor decodes a cursor created by encodeListCursor
+func decodeListCursor(raw, sort string) (*db.ListCursor, bool) {
+	values, err := service.DecodeCursor(raw, 3)
+	if err != nil || values[0] != sort {
+		return nil, false
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, values[1])
+	if err != nil {
+		return nil, false
+	}
+
+	return &db.ListCursor{Key: values[2], Time: at.UTC()}, true
+}
+
+// ListOptionsAction handles GET requests to list options. Options can be
+// filtered by key prefix and searched by key or value with q, sorted with
+// sort and order, and paginated with limit and the next_cursor of the
+// previous page. The X-Total-Count header holds the number of matches. Secret
+// values are masked unless reveal=true.
+func ListOptionsAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("List options endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	opts, ok := listOptions(w, r)
+	if !ok {
+		return
+	}
+
+	reveal := revealSecrets(r)
+
+	page, err := repo.Find(opts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list options")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list options",
+		})
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(page.Options))
+	for _, option := range page.Options {
+		items = append(items, optionResponse(option, reveal))
+	}
+
+	var next interface{}
+	if page.Next != nil {
+		next = encodeListCursor(page.Next, opts.Sort)
+	}
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"options":     items,
+		"next_cursor": next,
+	})
+}
+
+// GetOptionAction handles GET requests to retrieve an option by key. The value
+// of a secret option is only returned with reveal=true.
+func GetOptionAction(w http.ResponseWriter, r *http.Request) {
+	key, ok := optionKey(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("key", key).Msg("Get option endpoint called")
+
+	at, pointInTime, ok := parseAt(w, r)
+	if !ok {
+		return
+	}
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	if pointInTime {
+		revision, err := repo.GetAt(key, at)
+		if err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to get option revision")
+			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to retrieve option",
+			})
+			return
+		}
+
+		if revision == nil {
+			service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+				"error": "Option not found",
+			})
+			return
+		}
+
+		service.WriteJSON(w, http.StatusOK, revisionResponse(revision, revealSecrets(r)))
+		return
+	}
+
+	option, err := repo.Get(key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to get option")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve option",
+		})
+		return
+	}
+
+	if option == nil {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Option not found",
+		})
+		return
+	}
+
+	w.Header().Set("ETag", service.ETag(option.Version))
+	service.WriteJSON(w, http.StatusOK, optionResponse(option, revealSecrets(r)))
+}
+
+// PutOptionAction handles PUT requests to create or update an option
+func PutOptionAction(w http.ResponseWriter, r *http.Request) {
+	key, ok := optionKey(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("key", key).Msg("Put option endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	var req PutOptionRequest
+	if err := service.DecodeJSON(r, &req); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	value, err := service.NormalizeJSON(req.Value)
+	if err != nil {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Value must be a JSON document",
+		})
+		return
+	}
+
+	if req.TTL < 0 {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "TTL must not be negative",
+		})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.TTL > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.TTL) * time.Second)
+		expiresAt = &t
+	}
+
+	if req.Secret != nil {
+		repo = repo.WithSecret(*req.Secret)
+	}
+
+	option, status, ok := saveOption(w, r, repo, key, value, expiresAt)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("ETag", service.ETag(option.Version))
+	service.WriteJSON(w, status, optionResponse(option, false))
+}
+
+// patchOption applies the JSON Merge Patch or JSON Patch in the request body
+// to an option. The patch is applied atomically, honors the If-Match header and
+// the result must conform to the schema of the key.
+func patchOption(w http.ResponseWriter, r *http.Request, repo *db.OptionRepository, key string) (*db.Option, bool) {
+	var expected int64
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" {
+		version, ok := service.ParseETag(ifMatch)
+		if !ok {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid If-Match header",
+			})
+			return nil, false
+		}
+		expected = version
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return nil, false
+	}
+
+	var patchErr error
+	option, err := repo.Patch(key, expected, func(value string) (string, error) {
+		result, err := service.ApplyPatch(r.Header.Get("Content-Type"), []byte(value), patch)
+		if err != nil {
+			patchErr = err
+			return "", err
+		}
+
+		if err := repo.Schemas().Validate(key, result); err != nil {
+			return "", err
+		}
+
+		return result, nil
+	})
+
+	var violation *db.SchemaViolationError
+
+	switch {
+	case err == nil:
+		log.Info().Str("key", key).Msg("Option patched successfully")
+		return option, true
+	case errors.As(err, &violation):
+		writeSchemaViolations(w, violation)
+	case errors.Is(err, service.ErrUnsupportedPatch):
+		service.WriteJSON(w, http.StatusUnsupportedMediaType, map[string]interface{}{
+			"error": "Content-Type must be application/merge-patch+json or application/json-patch+json",
+		})
+	case patchErr != nil:
+		service.WriteJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": patchErr.Error(),
+		})
+	case errors.Is(err, db.ErrOptionNotFound):
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Option not found",
+		})
+	case errors.Is(err, db.ErrVersionMismatch):
+		service.WriteJSON(w, http.StatusPreconditionFailed, map[string]interface{}{
+			"error": "Option was modified by another request",
+		})
+	default:
+		log.Error().Err(err).Str("key", key).Msg("Failed to patch option")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to patch option",
+		})
+	}
+
+	return nil, false
+}
+
+// PatchOptionAction handles PATCH requests to partially update a JSON option value
+func PatchOptionAction(w http.ResponseWriter, r *http.Request) {
+	key, ok := optionKey(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("key", key).Msg("Patch option endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	option, ok := patchOption(w, r, repo, key)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("ETag", service.ETag(option.Version))
+	service.WriteJSON(w, http.StatusOK, optionResponse(option, false))
+}
+
+// DeleteOptionAction handles DELETE requests to move an option to the trash.
+// With force=true the option is removed for good, even from the trash.
+func DeleteOptionAction(w http.ResponseWriter, r *http.Request) {
+	key, ok := optionKey(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("key", key).Msg("Delete option endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("force") == "true" {
+		destroyed, err := repo.Destroy(key)
+		if err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to destroy option")
+			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to delete option",
+			})
+			return
+		}
+
+		if !destroyed {
+			service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+				"error": "Option not found",
+			})
+			return
+		}
+
+		log.Info().Str("key", key).Msg("Option destroyed successfully")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	existing, err := repo.Get(key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to check existing option")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to check option",
+		})
+		return
+	}
+
+	if existing == nil {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Option not found",
+		})
+		return
+	}
+
+	if err := repo.Delete(key); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to delete option")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to delete option",
+		})
+		return
+	}
+
+	log.Info().Str("key", key).Msg("Option deleted successfully")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 1000
+)
+
+// RevertOptionRequest represents the request body for reverting an option
+type RevertOptionRequest struct {
+	Revision int64 `json:"revision"`
+}
+
+// revisionResponse converts a revision into its JSON representation. The
+// value of a secret revision is masked unless revealed.
+func revisionResponse(revision *db.Revision, reveal bool) map[string]interface{} {
+	var value interface{}
+	if revision.Action != db.RevisionActionDelete {
+		value = json.RawMessage(revision.DisplayValue(reveal))
+	}
+
+	return map[string]interface{}{
+		"revision":   revision.ID,
+		"namespace":  revision.Namespace,
+		"key":        revision.Key,
+		"value":      value,
+		"secret":     revision.Secret,
+		"version":    revision.Version,
+		"action":     revision.Action,
+		"created_at": revision.CreatedAt,
+	}
+}
+
+// parseAt parses the point-in-time query parameter
+func parseAt(w http.ResponseWriter, r *http.Request) (time.Time, bool, bool) {
+	raw := r.URL.Query().Get("at")
+	if raw == "" {
+		return time.Time{}, false, true
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid at parameter, expected an RFC 3339 timestamp",
+		})
+		return time.Time{}, false, false
+	}
+
+	return at, true, true
+}
+
+// OptionHistoryAction handles GET requests to list the revisions of an option
+func OptionHistoryAction(w http.ResponseWriter, r *http.Request) {
+	key, ok := optionKey(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("key", key).Msg("Option history endpoint called")
+
+	limit := defaultHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > maxHistoryLimit {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid limit parameter",
+			})
+			return
+		}
+		limit = value
+	}
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := repo.History(key, limit)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to get option history")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve option history",
+		})
+		return
+	}
+
+	if len(revisions) == 0 {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Option not found",
+		})
+		return
+	}
+
+	reveal := revealSecrets(r)
+
+	items := make([]map[string]interface{}, 0, len(revisions))
+	for _, revision := range revisions {
+		items = append(items, revisionResponse(revision, reveal))
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace": repo.Namespace(),
+		"key":       key,
+		"revisions": items,
+	})
+}
+
+// RevertOptionAction handles POST requests to restore a prior revision of an option
+func RevertOptionAction(w http.ResponseWriter, r *http.Request) {
+	key, ok := optionKey(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("key", key).Msg("Revert option endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	var req RevertOptionRequest
+	if err := service.DecodeJSON(r, &req); err != nil || req.Revision < 1 {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	option, err := repo.Revert(key, req.Revision)
+	if errors.Is(err, db.ErrRevisionNotFound) {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Revision not found",
+		})
+		return
+	}
+	if errors.Is(err, db.ErrRevisionDeleted) {
+		service.WriteJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error": "Cannot revert to a delete revision",
+		})
+		return
+	}
+	var violation *db.SchemaViolationError
+	if errors.As(err, &violation) {
+		writeSchemaViolations(w, violation)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Int64("revision", req.Revision).Msg("Failed to revert option")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to revert option",
+		})
+		return
+	}
+
+	log.Info().Str("key", key).Int64("revision", req.Revision).Msg("Option reverted successfully")
+
+	w.Header().Set("ETag", service.ETag(option.Version))
+	service.WriteJSON(w, http.StatusOK, optionResponse(option, false))
+}
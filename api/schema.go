@@ -0,0 +1,249 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// PutSchemaRequest represents the request body for registering a schema
+type PutSchemaRequest struct {
+	// Schema is the JSON Schema document option values must conform to
+	Schema json.RawMessage `json:"schema"`
+}
+
+// schemaResponse converts a schema into its JSON representation
+func schemaResponse(schema *db.Schema) map[string]interface{} {
+	return map[string]interface{}{
+		"namespace":  schema.Namespace,
+		"prefix":     schema.Prefix,
+		"schema":     json.RawMessage(schema.Schema),
+		"created_at": schema.CreatedAt,
+		"updated_at": schema.UpdatedAt,
+	}
+}
+
+// schemaRepository returns a schema repository scoped to the namespace in the
+// URL, or the default namespace
+func schemaRepository(w http.ResponseWriter, r *http.Request) (*db.SchemaRepository, bool) {
+	options, ok := optionRepository(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	return db.NewSchemaRepository(db.GetDB()).WithNamespace(options.Namespace()), true
+}
+
+// schemaPrefix extracts and validates the key prefix from the URL
+func schemaPrefix(w http.ResponseWriter, r *http.Request) (string, bool) {
+	prefix := chi.URLParam(r, "prefix")
+
+	if !service.IsValidKey(prefix) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid schema prefix",
+		})
+		return "", false
+	}
+
+	return prefix, true
+}
+
+// validateOptionValue checks a value against the schema governing an option
+// key. It writes 422 Unprocessable Entity listing each violation if the value
+// does not conform.
+func validateOptionValue(w http.ResponseWriter, repo *db.OptionRepository, key, value string) bool {
+	err := repo.Schemas().Validate(key, value)
+	if err == nil {
+		return true
+	}
+
+	var violation *db.SchemaViolationError
+	if errors.As(err, &violation) {
+		writeSchemaViolations(w, violation)
+		return false
+	}
+
+	log.Error().Err(err).Str("key", key).Msg("Failed to validate option value")
+	service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+		"error": "Failed to validate option",
+	})
+	return false
+}
+
+// writeSchemaViolations writes the violations of a value against a schema
+func writeSchemaViolations(w http.ResponseWriter, violation *db.SchemaViolationError) {
+	service.WriteJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"error":      "Value does not conform to schema",
+		"key":        violation.Key,
+		"prefix":     violation.Schema.Prefix,
+		"violations": violation.Violations,
+	})
+}
+
+// ListSchemasAction handles GET requests to list the schemas of a namespace
+func ListSchemasAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("List schemas endpoint called")
+
+	repo, ok := schemaRepository(w, r)
+	if !ok {
+		return
+	}
+
+	schemas, err := repo.List()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list schemas")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list schemas",
+		})
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(schemas))
+	for _, schema := range schemas {
+		items = append(items, schemaResponse(schema))
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace": repo.Namespace(),
+		"schemas":   items,
+	})
+}
+
+// GetSchemaAction handles GET requests to retrieve the schema of a key prefix
+func GetSchemaAction(w http.ResponseWriter, r *http.Request) {
+	prefix, ok := schemaPrefix(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("prefix", prefix).Msg("Get schema endpoint called")
+
+	repo, ok := schemaRepository(w, r)
+	if !ok {
+		return
+	}
+
+	schema, err := repo.Get(prefix)
+	if err != nil {
+		log.Error().Err(err).Str("prefix", prefix).Msg("Failed to get schema")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve schema",
+		})
+		return
+	}
+
+	if schema == nil {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Schema not found",
+		})
+		return
+	}
+
+	service.WriteJSON(w, http.StatusOK, schemaResponse(schema))
+}
+
+// PutSchemaAction handles PUT requests to register the schema of a key prefix
+func PutSchemaAction(w http.ResponseWriter, r *http.Request) {
+	prefix, ok := schemaPrefix(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("prefix", prefix).Msg("Put schema endpoint called")
+
+	repo, ok := schemaRepository(w, r)
+	if !ok {
+		return
+	}
+
+	var req PutSchemaRequest
+	if err := service.DecodeJSON(r, &req); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	document, err := service.NormalizeJSON(req.Schema)
+	if err == nil {
+		_, err = service.CompileSchema(document)
+	}
+	if err != nil {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	existing, err := repo.Get(prefix)
+	if err != nil {
+		log.Error().Err(err).Str("prefix", prefix).Msg("Failed to check existing schema")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to check schema",
+		})
+		return
+	}
+
+	schema, err := repo.Save(prefix, document)
+	if err != nil {
+		log.Error().Err(err).Str("prefix", prefix).Msg("Failed to save schema")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to save schema",
+		})
+		return
+	}
+
+	log.Info().Str("prefix", prefix).Msg("Schema saved successfully")
+
+	status := http.StatusOK
+	if existing == nil {
+		status = http.StatusCreated
+	}
+
+	service.WriteJSON(w, status, schemaResponse(schema))
+}
+
+// DeleteSchemaAction handles DELETE requests to remove the schema of a key prefix
+func DeleteSchemaAction(w http.ResponseWriter, r *http.Request) {
+	prefix, ok := schemaPrefix(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("prefix", prefix).Msg("Delete schema endpoint called")
+
+	repo, ok := schemaRepository(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := repo.Delete(prefix)
+	if err != nil {
+		log.Error().Err(err).Str("prefix", prefix).Msg("Failed to delete schema")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to delete schema",
+		})
+		return
+	}
+
+	if !deleted {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Schema not found",
+		})
+		return
+	}
+
+	log.Info().Str("prefix", prefix).Msg("Schema deleted successfully")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,9 +5,11 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/middleware"
 	"github.com/clivern/zewi/service"
 
 	"github.com/rs/zerolog/log"
@@ -16,9 +18,14 @@ import (
 const stateKey = "state"
 
 // GetStateAction handles GET requests to retrieve the state
-func GetStateAction(w http.ResponseWriter, _ *http.Request) {
+func GetStateAction(w http.ResponseWriter, r *http.Request) {
 	log.Debug().Msg("Get state endpoint called")
 
+	at, pointInTime, ok := parseAt(w, r)
+	if !ok {
+		return
+	}
+
 	database := db.GetDB()
 	if database == nil {
 		log.Error().Msg("Database not initialized")
@@ -29,6 +36,28 @@ func GetStateAction(w http.ResponseWriter, _ *http.Request) {
 	}
 
 	repo := db.NewOptionRepository(database)
+
+	if pointInTime {
+		revision, err := repo.GetAt(stateKey, at)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get state revision from database")
+			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to retrieve state",
+			})
+			return
+		}
+
+		var state interface{}
+		if revision != nil {
+			state = json.RawMessage(revision.DisplayValue(revealSecrets(r)))
+		}
+
+		service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"state": state,
+		})
+		return
+	}
+
 	option, err := repo.Get(stateKey)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get state from database")
@@ -46,14 +75,16 @@ func GetStateAction(w http.ResponseWriter, _ *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", service.ETag(option.Version))
 	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
-		"state": option.Value,
+		"state": json.RawMessage(option.DisplayValue(revealSecrets(r))),
 	})
 }
 
 // UpdateStateRequest represents the request body for updating state
 type UpdateStateRequest struct {
-	Value string `json:"value"`
+	// Value is any JSON document
+	Value json.RawMessage `json:"value"`
 }
 
 // UpdateStateAction handles PUT/POST requests to update the state
@@ -78,42 +109,51 @@ func UpdateStateAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	repo := db.NewOptionRepository(database)
-
-	// Check if state exists
-	existing, err := repo.Get(stateKey)
+	value, err := service.NormalizeJSON(req.Value)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to check existing state")
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Value must be a JSON document",
+		})
+		return
+	}
+
+	repo := db.NewOptionRepository(database).WithAuditTrail(middleware.GetAuditTrail(r.Context()))
+
+	option, _, ok := saveOption(w, r, repo, stateKey, value, nil)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("ETag", service.ETag(option.Version))
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "State updated successfully",
+		"state":   json.RawMessage(option.DisplayValue(false)),
+	})
+}
+
+// PatchStateAction handles PATCH requests to partially update the state
+func PatchStateAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("Patch state endpoint called")
+
+	database := db.GetDB()
+	if database == nil {
+		log.Error().Msg("Database not initialized")
 		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Failed to check state",
+			"error": "Database not initialized",
 		})
 		return
 	}
 
-	if existing == nil {
-		// Create new state option
-		if err := repo.Create(stateKey, req.Value); err != nil {
-			log.Error().Err(err).Msg("Failed to create state")
-			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
-				"error": "Failed to create state",
-			})
-			return
-		}
-		log.Info().Msg("State created successfully")
-	} else {
-		// Update existing state
-		if err := repo.Update(stateKey, req.Value); err != nil {
-			log.Error().Err(err).Msg("Failed to update state")
-			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
-				"error": "Failed to update state",
-			})
-			return
-		}
-		log.Info().Msg("State updated successfully")
+	repo := db.NewOptionRepository(database).WithAuditTrail(middleware.GetAuditTrail(r.Context()))
+
+	option, ok := patchOption(w, r, repo, stateKey)
+	if !ok {
+		return
 	}
 
+	w.Header().Set("ETag", service.ETag(option.Version))
 	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "State updated successfully",
-		"state":   req.Value,
+		"state":   json.RawMessage(option.DisplayValue(false)),
 	})
 }
@@ -0,0 +1,196 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// streamHeartbeat is how often an idle stream sends a comment to keep proxies from closing it
+	streamHeartbeat = 30 * time.Second
+	// streamBatchSize is the number of revisions read from the history at once
+	streamBatchSize = 100
+	// streamBuffer is the number of pending change hints kept per stream
+	streamBuffer = 16
+	// streamSettleDelay is how long after a change streams check again for
+	// revisions held back until concurrent transactions settled
+	streamSettleDelay = time.Second
+)
+
+// streamChanges pushes the revisions of a namespace as Server-Sent Events,
+// limited to one option if key is not empty. Each event id is the revision
+// number so clients can resume with the Last-Event-ID header.
+func streamChanges(w http.ResponseWriter, r *http.Request, repo *db.OptionRepository, key string) {
+	var after int64
+	resume := r.Header.Get("Last-Event-ID")
+	if resume != "" {
+		id, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || id < 0 {
+			service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid Last-Event-ID header",
+			})
+			return
+		}
+		after = id
+	}
+
+	// Subscribe before reading the history so no change falls in between
+	changes, unsubscribe := db.SubscribeChanges(streamBuffer)
+	defer unsubscribe()
+
+	if resume == "" {
+		latest, err := repo.LatestRevisionID()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to read latest revision")
+			service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to open stream",
+			})
+			return
+		}
+		after = latest
+	}
+
+	rc := http.NewResponseController(w)
+	// Streams outlive the server write timeout, ignore writers that can not lift it
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		log.Error().Err(err).Msg("Streaming is not supported by the response writer")
+		return
+	}
+
+	send := func() error {
+		for {
+			revisions, err := repo.RevisionsSince(after, key, streamBatchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, revision := range revisions {
+				data, err := json.Marshal(revisionResponse(revision, false))
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: change\ndata: %s\n\n", revision.ID, data); err != nil {
+					return err
+				}
+				after = revision.ID
+			}
+
+			if len(revisions) < streamBatchSize {
+				return rc.Flush()
+			}
+		}
+	}
+
+	if resume != "" {
+		if err := send(); err != nil {
+			log.Debug().Err(err).Msg("Stream closed")
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	// Revisions may be held back until concurrent transactions settle, so the
+	// stream checks again a little after each change and on every heartbeat
+	settle := time.NewTimer(streamSettleDelay)
+	defer settle.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			if change.Namespace != "" && (change.Namespace != repo.Namespace() || (key != "" && change.Key != key)) {
+				continue
+			}
+
+			if err := send(); err != nil {
+				log.Debug().Err(err).Msg("Stream closed")
+				return
+			}
+			settle.Reset(streamSettleDelay)
+		case <-settle.C:
+			if err := send(); err != nil {
+				log.Debug().Err(err).Msg("Stream closed")
+				return
+			}
+		case <-heartbeat.C:
+			if err := send(); err != nil {
+				log.Debug().Err(err).Msg("Stream closed")
+				return
+			}
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// StreamStateAction handles GET requests to stream state changes
+func StreamStateAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("Stream state endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	streamChanges(w, r, repo, stateKey)
+}
+
+// StreamOptionsAction handles GET requests to stream the changes of every option in a namespace
+func StreamOptionsAction(w http.ResponseWriter, r *http.Request) {
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("namespace", repo.Namespace()).Msg("Stream options endpoint called")
+
+	streamChanges(w, r, repo, "")
+}
+
+// StreamOptionAction handles GET requests to stream the changes of an option
+func StreamOptionAction(w http.ResponseWriter, r *http.Request) {
+	key, ok := optionKey(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("key", key).Msg("Stream option endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	streamChanges(w, r, repo, key)
+}
@@ -0,0 +1,208 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// documentContentTypes maps options document formats to their media type
+var documentContentTypes = map[string]string{
+	service.FormatJSON: "application/json",
+	service.FormatYAML: "application/yaml",
+}
+
+// importFormat returns the format of the options document in the request
+// body, from the format query parameter or the Content-Type header
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return service.FormatYAML
+	default:
+		return service.FormatJSON
+	}
+}
+
+// ExportOptionsAction handles GET requests to export the options of a
+// namespace. Secret values are masked unless reveal=true.
+func ExportOptionsAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("Export options endpoint called")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.FormatJSON
+	}
+
+	if !service.IsValidFormat(format) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid format parameter, expected json or yaml",
+		})
+		return
+	}
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	options, err := repo.List()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list options")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to export options",
+		})
+		return
+	}
+
+	reveal := revealSecrets(r)
+
+	values := make(map[string]string, len(options))
+	var secrets []string
+	for _, option := range options {
+		values[option.Key] = option.DisplayValue(reveal)
+		if option.Secret {
+			secrets = append(secrets, option.Key)
+		}
+	}
+
+	data, err := service.EncodeOptions(format, repo.Namespace(), values, secrets)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode options")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to export options",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", documentContentTypes[format])
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ImportOptionsAction handles POST requests to import an options document into
+// a namespace. The mode query parameter is merge or replace and dry_run=true
+// only reports what would change.
+func ImportOptionsAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("Import options endpoint called")
+
+	format := importFormat(r)
+	if !service.IsValidFormat(format) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid format parameter, expected json or yaml",
+		})
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = db.ImportModeMerge
+	}
+
+	if mode != db.ImportModeMerge && mode != db.ImportModeReplace {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid mode parameter, expected merge or replace",
+		})
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	values, secrets, err := service.DecodeOptions(format, data)
+	if err != nil {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	report, err := repo.Import(values, secrets, mode, dryRun)
+
+	var violation *db.SchemaViolationError
+	if errors.As(err, &violation) {
+		writeSchemaViolations(w, violation)
+		return
+	}
+	if errors.Is(err, db.ErrMaskedSecret) {
+		service.WriteJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if encryptionDisabled(w, err) {
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to import options")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to import options",
+		})
+		return
+	}
+
+	log.Info().
+		Str("namespace", repo.Namespace()).
+		Str("mode", mode).
+		Bool("dry_run", dryRun).
+		Int("created", len(report.Created)).
+		Int("updated", len(report.Updated)).
+		Int("deleted", len(report.Deleted)).
+		Msg("Options imported successfully")
+
+	changes := make([]map[string]interface{}, 0, len(report.Changes))
+	for _, change := range report.Changes {
+		changes = append(changes, map[string]interface{}{
+			"key":    change.Key,
+			"action": change.Action,
+			"before": rawValue(change.Before),
+			"after":  rawValue(change.After),
+		})
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"namespace": repo.Namespace(),
+		"mode":      mode,
+		"dry_run":   dryRun,
+		"created":   report.Created,
+		"updated":   report.Updated,
+		"deleted":   report.Deleted,
+		"unchanged": report.Unchanged,
+		"changes":   changes,
+	})
+}
+
+// rawValue returns an option value as raw JSON, or nil if there is none
+func rawValue(value *string) interface{} {
+	if value == nil {
+		return nil
+	}
+	return json.RawMessage(*value)
+}
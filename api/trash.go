@@ -0,0 +1,83 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TrashOptionsAction handles GET requests to list the deleted options that can
+// still be restored
+func TrashOptionsAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("List trash endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	options, err := repo.Trash()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list trash")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to list trash",
+		})
+		return
+	}
+
+	reveal := revealSecrets(r)
+
+	items := make([]map[string]interface{}, 0, len(options))
+	for _, option := range options {
+		item := optionResponse(option, reveal)
+		item["deleted_at"] = option.DeletedAt
+		items = append(items, item)
+	}
+
+	service.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"options": items,
+	})
+}
+
+// RestoreOptionAction handles POST requests to move a deleted option out of
+// the trash
+func RestoreOptionAction(w http.ResponseWriter, r *http.Request) {
+	key, ok := optionKey(w, r)
+	if !ok {
+		return
+	}
+
+	log.Debug().Str("key", key).Msg("Restore option endpoint called")
+
+	repo, ok := optionRepository(w, r)
+	if !ok {
+		return
+	}
+
+	option, err := repo.Restore(key)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to restore option")
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to restore option",
+		})
+		return
+	}
+
+	if option == nil {
+		service.WriteJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "Option not found in trash",
+		})
+		return
+	}
+
+	log.Info().Str("key", key).Msg("Option restored successfully")
+
+	w.Header().Set("ETag", service.ETag(option.Version))
+	service.WriteJSON(w, http.StatusOK, optionResponse(option, false))
+}
@@ -0,0 +1,539 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/middleware"
+	"github.com/clivern/zewi/service"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// wsWriteWait is the time allowed to write a message to the client
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is the time allowed to read the next pong from the client
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod is how often pings are sent, it must be less than wsPongWait
+	wsPingPeriod = (wsPongWait * 9) / 10
+	// wsMaxMessageSize is the maximum size of a message from the client
+	wsMaxMessageSize = 1 << 20
+	// wsSendBuffer is the number of outgoing messages queued per client before
+	// it is disconnected as a slow consumer
+	wsSendBuffer = 64
+	// wsAuditMethod is the method audit events of WebSocket operations record
+	wsAuditMethod = "WS"
+)
+
+// upgrader upgrades HTTP connections to WebSocket. Browsers send cookies and
+// basic auth credentials with the handshake whatever its origin, so only the
+// same origin and the configured origins are accepted.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkOrigin,
+}
+
+// wsAllowedOrigins lists the origins besides the same origin allowed to open
+// a WebSocket, "*" allows every origin
+var wsAllowedOrigins []string
+
+// SetWebSocketOrigins sets the origins besides the same origin allowed to
+// open a WebSocket
+func SetWebSocketOrigins(origins []string) {
+	wsAllowedOrigins = origins
+}
+
+// checkOrigin accepts handshakes without an origin, as sent by non browser
+// clients, from the same origin or from an allowed origin
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range wsAllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebSocketRequest represents a message sent by a WebSocket client
+type WebSocketRequest struct {
+	// ID is echoed back in the reply so clients can match replies to requests
+	ID string `json:"id"`
+	// Op is one of subscribe, unsubscribe or set
+	Op        string `json:"op"`
+	Namespace string `json:"namespace"`
+	// Keys are the option keys to subscribe to or unsubscribe from
+	Keys []string `json:"keys"`
+	// Key, Value and Version describe a set operation, a non zero Version
+	// only updates the option if its version still matches
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value"`
+	Version int64           `json:"version"`
+}
+
+// wsSubscription identifies an option a client is subscribed to
+type wsSubscription struct {
+	namespace string
+	key       string
+}
+
+// wsClient is a connected WebSocket client
+type wsClient struct {
+	conn *websocket.Conn
+	send chan []byte
+	done chan struct{}
+
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+
+	mu            sync.Mutex
+	subscriptions map[wsSubscription]int64
+
+	// audit describes the connection in the audit events of set operations
+	audit db.AuditEvent
+}
+
+// WebSocketAction handles WebSocket connections subscribing to and setting options
+func WebSocketAction(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Msg("WebSocket endpoint called")
+
+	if db.GetDB() == nil {
+		service.WriteJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": "Database not initialized",
+		})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to upgrade WebSocket connection")
+		return
+	}
+
+	middleware.WebSocketConnections.Inc()
+	defer middleware.WebSocketConnections.Dec()
+
+	changes, unsubscribe := db.SubscribeChanges(streamBuffer)
+	defer unsubscribe()
+
+	client := &wsClient{
+		conn:          conn,
+		send:          make(chan []byte, wsSendBuffer),
+		done:          make(chan struct{}),
+		closeCode:     websocket.CloseNormalClosure,
+		subscriptions: make(map[wsSubscription]int64),
+		audit: db.AuditEvent{
+			RequestID: middleware.GetRequestID(r.Context()),
+			Actor:     middleware.GetActor(r.Context()),
+			RemoteIP:  middleware.RemoteIP(r),
+			Method:    wsAuditMethod,
+			Path:      r.URL.Path,
+		},
+	}
+
+	go client.writePump()
+	go client.changePump(changes)
+
+	client.readPump()
+	client.close(websocket.CloseNormalClosure, "")
+}
+
+// close stops the client and has the write pump send a close frame
+func (c *wsClient) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// enqueue queues a message for the client. A client whose queue is full can
+// not keep up and is disconnected rather than slowing down everyone else.
+func (c *wsClient) enqueue(message map[string]interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode WebSocket message")
+		return
+	}
+
+	select {
+	case <-c.done:
+	case c.send <- data:
+	default:
+		middleware.WebSocketSlowConsumersTotal.Inc()
+		log.Warn().Msg("Disconnecting slow WebSocket client")
+		c.close(websocket.CloseTryAgainLater, "slow consumer")
+	}
+}
+
+// reply queues a reply to a client request
+func (c *wsClient) reply(req *WebSocketRequest, message map[string]interface{}) {
+	message["id"] = req.ID
+	message["op"] = req.Op
+	c.enqueue(message)
+}
+
+// replyError queues an error reply to a client request
+func (c *wsClient) replyError(req *WebSocketRequest, message string) {
+	c.reply(req, map[string]interface{}{
+		"type":  "error",
+		"error": message,
+	})
+}
+
+// writePump writes queued messages and pings to the connection. It is the
+// only goroutine writing data messages.
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+			middleware.WebSocketMessagesTotal.WithLabelValues("out").Inc()
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(c.closeCode, c.closeReason),
+				time.Now().Add(wsWriteWait),
+			)
+			return
+		}
+	}
+}
+
+// readPump reads and handles client requests until the connection fails or
+// the client goes quiet for longer than wsPongWait.
+func (c *wsClient) readPump() {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Debug().Err(err).Msg("WebSocket connection closed")
+			}
+			return
+		}
+
+		middleware.WebSocketMessagesTotal.WithLabelValues("in").Inc()
+
+		var req WebSocketRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.replyError(&req, "Invalid message")
+			continue
+		}
+
+		c.handle(&req)
+	}
+}
+
+// changePump delivers the changes of subscribed options until the client
+// disconnects or the change feed is closed. Every subscription is checked
+// again a little after a change and then periodically, for the revisions held
+// back until concurrent transactions settled.
+func (c *wsClient) changePump(changes <-chan db.Change) {
+	settle := time.NewTimer(streamSettleDelay)
+	defer settle.Stop()
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case change, ok := <-changes:
+			if !ok {
+				c.close(websocket.CloseGoingAway, "server shutting down")
+				return
+			}
+			c.deliver(change)
+			settle.Reset(streamSettleDelay)
+		case <-settle.C:
+			c.deliver(db.Change{})
+		case <-ticker.C:
+			c.deliver(db.Change{})
+		}
+	}
+}
+
+// deliver queues the revisions recorded since the last delivery for every
+// subscription matching the change. Revisions are read without holding the
+// lock, so slow queries do not stall requests on the connection.
+func (c *wsClient) deliver(change db.Change) {
+	c.mu.Lock()
+	pending := make(map[wsSubscription]int64)
+	for subscription, after := range c.subscriptions {
+		if change.Namespace != "" && (change.Namespace != subscription.namespace || change.Key != subscription.key) {
+			continue
+		}
+		pending[subscription] = after
+	}
+	c.mu.Unlock()
+
+	database := db.GetDB()
+
+	for subscription, after := range pending {
+		repo := db.NewOptionRepository(database).WithNamespace(subscription.namespace)
+
+		for {
+			revisions, err := repo.RevisionsSince(after, subscription.key, streamBatchSize)
+			if err != nil {
+				log.Error().Err(err).Str("key", subscription.key).Msg("Failed to read option changes")
+				break
+			}
+
+			if !c.queueRevisions(subscription, after, revisions) {
+				break
+			}
+			if len(revisions) > 0 {
+				after = revisions[len(revisions)-1].ID
+			}
+
+			if len(revisions) < streamBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// queueRevisions queues the revisions read after a subscription cursor and
+// moves the cursor past them. Revision ids are not ordered on postgres, so the
+// cursor is compared rather than the ids. It returns false if the client
+// unsubscribed or subscribed again meanwhile.
+func (c *wsClient) queueRevisions(subscription wsSubscription, after int64, revisions []*db.Revision) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cursor, ok := c.subscriptions[subscription]; !ok || cursor != after {
+		return false
+	}
+
+	for _, revision := range revisions {
+		message := revisionResponse(revision, false)
+		message["type"] = "change"
+		c.enqueue(message)
+		after = revision.ID
+	}
+
+	c.subscriptions[subscription] = after
+	return true
+}
+
+// handle executes a client request
+func (c *wsClient) handle(req *WebSocketRequest) {
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = db.DefaultNamespace
+	}
+
+	if !service.IsValidKey(namespace) {
+		c.replyError(req, "Invalid namespace")
+		return
+	}
+
+	repo := db.NewOptionRepository(db.GetDB()).WithNamespace(namespace)
+
+	switch req.Op {
+	case "subscribe", "unsubscribe":
+		if len(req.Keys) == 0 {
+			c.replyError(req, "At least one key is required")
+			return
+		}
+		for _, key := range req.Keys {
+			if !service.IsValidKey(key) {
+				c.replyError(req, "Invalid option key")
+				return
+			}
+		}
+
+		if req.Op == "subscribe" {
+			c.subscribe(req, repo)
+		} else {
+			c.unsubscribe(req, repo)
+		}
+	case "set":
+		c.set(req, repo)
+	default:
+		c.replyError(req, "Unsupported operation")
+	}
+}
+
+// subscribe starts delivering the changes of the requested keys
+func (c *wsClient) subscribe(req *WebSocketRequest, repo *db.OptionRepository) {
+	latest, err := repo.LatestRevisionID()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to read latest revision")
+		c.replyError(req, "Failed to subscribe")
+		return
+	}
+
+	c.mu.Lock()
+	for _, key := range req.Keys {
+		subscription := wsSubscription{namespace: repo.Namespace(), key: key}
+		if _, ok := c.subscriptions[subscription]; !ok {
+			c.subscriptions[subscription] = latest
+		}
+	}
+	c.mu.Unlock()
+
+	c.reply(req, map[string]interface{}{
+		"type":      "subscribed",
+		"namespace": repo.Namespace(),
+		"keys":      req.Keys,
+	})
+}
+
+// unsubscribe stops delivering the changes of the requested keys
+func (c *wsClient) unsubscribe(req *WebSocketRequest, repo *db.OptionRepository) {
+	c.mu.Lock()
+	for _, key := range req.Keys {
+		delete(c.subscriptions, wsSubscription{namespace: repo.Namespace(), key: key})
+	}
+	c.mu.Unlock()
+
+	c.reply(req, map[string]interface{}{
+		"type":      "unsubscribed",
+		"namespace": repo.Namespace(),
+		"keys":      req.Keys,
+	})
+}
+
+// set creates or updates an option, honoring the expected version if given.
+// Like a PUT through the API, the operation is audited with the status the
+// API would answer.
+func (c *wsClient) set(req *WebSocketRequest, repo *db.OptionRepository) {
+	trail := db.NewAuditTrail()
+	repo = repo.WithAuditTrail(trail)
+
+	status := http.StatusOK
+	defer func() {
+		event := c.audit
+		event.Namespace = repo.Namespace()
+		event.Key = req.Key
+		event.Status = status
+		middleware.RecordAudit(&event, trail)
+	}()
+
+	if !service.IsValidKey(req.Key) {
+		status = http.StatusBadRequest
+		c.replyError(req, "Invalid option key")
+		return
+	}
+
+	value, err := service.NormalizeJSON(req.Value)
+	if err != nil {
+		status = http.StatusBadRequest
+		c.replyError(req, "Value must be a JSON document")
+		return
+	}
+
+	var violation *db.SchemaViolationError
+	if err := repo.Schemas().Validate(req.Key, value); errors.As(err, &violation) {
+		status = http.StatusUnprocessableEntity
+		c.reply(req, map[string]interface{}{
+			"type":       "error",
+			"error":      "Value does not conform to schema",
+			"prefix":     violation.Schema.Prefix,
+			"violations": violation.Violations,
+		})
+		return
+	} else if err != nil {
+		status = http.StatusInternalServerError
+		log.Error().Err(err).Str("key", req.Key).Msg("Failed to validate option value")
+		c.replyError(req, "Failed to validate option")
+		return
+	}
+
+	var option *db.Option
+	if req.Version > 0 {
+		option, err = repo.Patch(req.Key, req.Version, func(string) (string, error) {
+			return value, nil
+		})
+	} else {
+		// The option keeps its expiry, as when it is patched through the API
+		err = repo.Transaction(func(tx *db.OptionRepository) error {
+			current, err := tx.Get(req.Key)
+			if err != nil {
+				return err
+			}
+
+			var expiresAt *time.Time
+			if current != nil {
+				expiresAt = current.ExpiresAt
+			}
+
+			option, _, err = tx.Upsert(req.Key, value, expiresAt)
+			return err
+		})
+	}
+
+	switch {
+	case errors.Is(err, db.ErrOptionNotFound):
+		status = http.StatusNotFound
+		c.replyError(req, "Option does not exist")
+		return
+	case errors.Is(err, db.ErrVersionMismatch):
+		status = http.StatusPreconditionFailed
+		c.replyError(req, "Option was modified by another request")
+		return
+	case err != nil:
+		status = http.StatusInternalServerError
+		log.Error().Err(err).Str("key", req.Key).Msg("Failed to save option")
+		c.replyError(req, "Failed to save option")
+		return
+	}
+
+	log.Info().Str("key", req.Key).Msg("Option saved over WebSocket")
+
+	c.reply(req, map[string]interface{}{
+		"type":   "result",
+		"option": optionResponse(option, false),
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/internal/testsupport"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB initializes the global connection with a migrated sqlite
+// database, closed when the test ends
+func newTestDB(t *testing.T) {
+	t.Helper()
+
+	err := db.InitDB(db.Config{Driver: "sqlite", DataSource: testsupport.SQLite(t)})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.CloseDB() })
+}
+
+func TestUnitWebSocket(t *testing.T) {
+	t.Run("Set keeps the expiry of the option", func(t *testing.T) {
+		newTestDB(t)
+
+		repo := db.NewOptionRepository(db.GetDB())
+		expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+		_, _, err := repo.Upsert("app.name", `"zewi"`, &expiresAt)
+		require.NoError(t, err)
+
+		server := httptest.NewServer(http.HandlerFunc(WebSocketAction))
+		defer server.Close()
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{
+			"id":    "1",
+			"op":    "set",
+			"key":   "app.name",
+			"value": "zewi 2",
+		}))
+
+		var reply map[string]interface{}
+		require.NoError(t, conn.ReadJSON(&reply))
+		assert.Equal(t, "result", reply["type"])
+
+		option, err := repo.Get("app.name")
+		assert.NoError(t, err)
+		assert.Equal(t, `"zewi 2"`, option.Value)
+		require.NotNil(t, option.ExpiresAt)
+		assert.True(t, expiresAt.Equal(*option.ExpiresAt))
+	})
+}
+
+func TestUnitCheckOrigin(t *testing.T) {
+	t.Run("Accepts the same origin and requests without one", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://zewi.example.com/api/v1/ws", nil)
+		assert.True(t, checkOrigin(r))
+
+		r.Header.Set("Origin", "http://zewi.example.com")
+		assert.True(t, checkOrigin(r))
+	})
+
+	t.Run("Refuses other origins unless allowed", func(t *testing.T) {
+		defer SetWebSocketOrigins(nil)
+
+		r := httptest.NewRequest(http.MethodGet, "http://zewi.example.com/api/v1/ws", nil)
+		r.Header.Set("Origin", "http://evil.example.com")
+		assert.False(t, checkOrigin(r))
+
+		SetWebSocketOrigins([]string{"http://evil.example.com/"})
+		assert.True(t, checkOrigin(r))
+
+		SetWebSocketOrigins([]string{"*"})
+		assert.True(t, checkOrigin(r))
+	})
+}
@@ -0,0 +1,220 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clivern/zewi/core"
+	"github.com/clivern/zewi/db"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// auditExportPageSize is the number of audit events read per query
+const auditExportPageSize = 1000
+
+var (
+	// auditFile is the file to export audit events to
+	auditFile string
+	// auditActor, auditNamespace and auditKey filter the exported events
+	auditActor     string
+	auditNamespace string
+	auditKey       string
+	// auditSince and auditUntil bound the exported events in time
+	auditSince string
+	auditUntil string
+)
+
+// auditColumns are the fields of an exported audit event
+var auditColumns = []string{
+	"id",
+	"created_at",
+	"request_id",
+	"actor",
+	"remote_ip",
+	"method",
+	"path",
+	"namespace",
+	"key",
+	"old_value_hash",
+	"new_value_hash",
+	"status",
+	"result",
+}
+
+// auditRecord converts an audit event into the values of auditColumns
+func auditRecord(event *db.AuditEvent) []string {
+	return []string{
+		strconv.FormatInt(event.ID, 10),
+		event.CreatedAt.Format(time.RFC3339Nano),
+		event.RequestID,
+		event.Actor,
+		event.RemoteIP,
+		event.Method,
+		event.Path,
+		event.Namespace,
+		event.Key,
+		event.OldValueHash,
+		event.NewValueHash,
+		strconv.Itoa(event.Status),
+		event.Result,
+	}
+}
+
+// parseAuditTime parses an optional RFC 3339 time flag
+func parseAuditTime(name, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Fatal().Err(err).Str("flag", name).Msg("Invalid time, expected RFC 3339")
+	}
+	return &at
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Audit log commands",
+	Long:  `Export the audit log of mutating API calls`,
+}
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export audit events to a JSON Lines or CSV file",
+	Run: func(cmd *cobra.Command, _ []string) {
+		configFile, _ := cmd.Flags().GetString("config")
+
+		if err := core.Load(configFile); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load configuration")
+		}
+
+		if err := core.SetupLogging(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to setup logging")
+		}
+
+		filter := db.AuditFilter{
+			Actor:     auditActor,
+			Namespace: auditNamespace,
+			Key:       auditKey,
+			Since:     parseAuditTime("since", auditSince),
+			Until:     parseAuditTime("until", auditUntil),
+			Limit:     auditExportPageSize,
+		}
+
+		if err := core.InitDatabaseAPI(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to connect to database")
+		}
+		defer db.CloseDB()
+
+		file, err := os.Create(auditFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create audit file")
+		}
+		defer file.Close()
+
+		out := bufio.NewWriter(file)
+
+		var write func(*db.AuditEvent) error
+		flush := out.Flush
+
+		if strings.EqualFold(filepath.Ext(auditFile), ".csv") {
+			writer := csv.NewWriter(out)
+			flush = func() error {
+				writer.Flush()
+				if err := writer.Error(); err != nil {
+					return err
+				}
+				return out.Flush()
+			}
+
+			if err := writer.Write(auditColumns); err != nil {
+				log.Fatal().Err(err).Msg("Failed to write audit file")
+			}
+			write = func(event *db.AuditEvent) error {
+				return writer.Write(auditRecord(event))
+			}
+		} else {
+			encoder := json.NewEncoder(out)
+			write = func(event *db.AuditEvent) error {
+				record := make(map[string]interface{}, len(auditColumns))
+				for i, value := range auditRecord(event) {
+					record[auditColumns[i]] = value
+				}
+				record["id"] = event.ID
+				record["status"] = event.Status
+				return encoder.Encode(record)
+			}
+		}
+
+		repo := db.NewAuditRepository(db.GetDB())
+		count := 0
+
+		for {
+			events, err := repo.Find(filter)
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to read audit events")
+			}
+
+			for _, event := range events {
+				if err := write(event); err != nil {
+					log.Fatal().Err(err).Msg("Failed to write audit file")
+				}
+			}
+			count += len(events)
+
+			if len(events) < filter.Limit {
+				break
+			}
+			filter.BeforeID = events[len(events)-1].ID
+		}
+
+		if err := flush(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to write audit file")
+		}
+
+		log.Info().
+			Str("file", auditFile).
+			Int("count", count).
+			Msg("Audit events exported successfully")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditExportCmd)
+
+	auditExportCmd.Flags().StringVarP(
+		&config,
+		"config",
+		"c",
+		"config.prod.yml",
+		"Absolute path to config file (required)",
+	)
+	auditExportCmd.MarkFlagRequired("config")
+	auditExportCmd.Flags().StringVarP(
+		&auditFile,
+		"file",
+		"f",
+		"",
+		"Path to the audit file, CSV if it ends with .csv and JSON Lines otherwise (required)",
+	)
+	auditExportCmd.MarkFlagRequired("file")
+	auditExportCmd.Flags().StringVar(&auditActor, "actor", "", "Only export events of this actor")
+	auditExportCmd.Flags().StringVarP(&auditNamespace, "namespace", "n", "", "Only export events of this namespace")
+	auditExportCmd.Flags().StringVar(&auditKey, "key", "", "Only export events of this option key")
+	auditExportCmd.Flags().StringVar(&auditSince, "since", "", "Only export events at or after this RFC 3339 time")
+	auditExportCmd.Flags().StringVar(&auditUntil, "until", "", "Only export events before this RFC 3339 time")
+}
@@ -5,6 +5,10 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/clivern/zewi/core"
 	"github.com/clivern/zewi/db"
 	"github.com/clivern/zewi/migration"
@@ -14,6 +18,68 @@ import (
 	"github.com/spf13/viper"
 )
 
+var (
+	// allowDrift lets migrate up run although applied migrations drifted
+	allowDrift bool
+	// migrationsDir is the directory migrate create writes the SQL migration to
+	migrationsDir string
+	// upTo is the version migrate up stops at
+	upTo string
+	// downSteps is the number of migrations migrate down rolls back
+	downSteps int
+	// downTo is the version migrate down rolls back to
+	downTo string
+)
+
+// openMigrationManager loads the configuration, connects to the database and
+// returns a migration manager with the Go and the embedded SQL migrations
+func openMigrationManager(configFile string) (*migration.Manager, *db.Connection) {
+	if err := core.Load(configFile); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	if err := core.SetupLogging(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to setup logging")
+	}
+
+	// Initialize database connection
+	dbConfig := db.Config{
+		Driver:          viper.GetString("app.database.driver"),
+		Host:            viper.GetString("app.database.host"),
+		Port:            viper.GetInt("app.database.port"),
+		Username:        viper.GetString("app.database.username"),
+		Password:        viper.GetString("app.database.password"),
+		Database:        viper.GetString("app.database.name"),
+		MaxOpenConns:    viper.GetInt("app.database.max_open_conns"),
+		MaxIdleConns:    viper.GetInt("app.database.max_idle_conns"),
+		ConnMaxLifetime: viper.GetInt("app.database.conn_max_lifetime"),
+		DataSource:      viper.GetString("app.database.datasource"),
+	}
+
+	conn, err := db.NewConnection(dbConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+
+	// Create migration manager
+	mgr := migration.NewManager(conn.DB, conn.Driver)
+
+	// Register all migrations
+	for _, m := range migration.GetAll() {
+		mgr.Register(m)
+	}
+
+	if err := mgr.RegisterSQL(migration.Files()); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load SQL migrations")
+	}
+
+	if viper.IsSet("app.database.migration_lock_timeout") {
+		mgr.SetLockTimeout(time.Duration(viper.GetInt("app.database.migration_lock_timeout")) * time.Second)
+	}
+
+	return mgr, conn
+}
+
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Database migration commands",
@@ -22,48 +88,17 @@ var migrateCmd = &cobra.Command{
 
 var migrateUpCmd = &cobra.Command{
 	Use:   "up",
-	Short: "Run all pending migrations",
+	Short: "Run all pending migrations, or those up to a version",
 	Run: func(cmd *cobra.Command, _ []string) {
 		configFile, _ := cmd.Flags().GetString("config")
 
-		if err := core.Load(configFile); err != nil {
-			log.Fatal().Err(err).Msg("Failed to load configuration")
-		}
-
-		if err := core.SetupLogging(); err != nil {
-			log.Fatal().Err(err).Msg("Failed to setup logging")
-		}
-
-		// Initialize database connection
-		dbConfig := db.Config{
-			Driver:          viper.GetString("app.database.driver"),
-			Host:            viper.GetString("app.database.host"),
-			Port:            viper.GetInt("app.database.port"),
-			Username:        viper.GetString("app.database.username"),
-			Password:        viper.GetString("app.database.password"),
-			Database:        viper.GetString("app.database.name"),
-			MaxOpenConns:    viper.GetInt("app.database.max_open_conns"),
-			MaxIdleConns:    viper.GetInt("app.database.max_idle_conns"),
-			ConnMaxLifetime: viper.GetInt("app.database.conn_max_lifetime"),
-			DataSource:      viper.GetString("app.database.datasource"),
-		}
-
-		conn, err := db.NewConnection(dbConfig)
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to connect to database")
-		}
+		mgr, conn := openMigrationManager(configFile)
 		defer conn.Close()
 
-		// Create migration manager
-		mgr := migration.NewManager(conn.DB, conn.Driver)
-
-		// Register all migrations
-		for _, m := range migration.GetAll() {
-			mgr.Register(m)
-		}
+		mgr.SetAllowDrift(allowDrift)
 
 		// Run migrations
-		if err := mgr.Up(); err != nil {
+		if err := mgr.UpTo(upTo); err != nil {
 			log.Fatal().Err(err).Msg("Failed to run migrations")
 		}
 
@@ -73,48 +108,21 @@ var migrateUpCmd = &cobra.Command{
 
 var migrateDownCmd = &cobra.Command{
 	Use:   "down",
-	Short: "Roll back the last migration",
+	Short: "Roll back the last migrations, or those applied after a version",
 	Run: func(cmd *cobra.Command, _ []string) {
 		configFile, _ := cmd.Flags().GetString("config")
 
-		if err := core.Load(configFile); err != nil {
-			log.Fatal().Err(err).Msg("Failed to load configuration")
-		}
-
-		if err := core.SetupLogging(); err != nil {
-			log.Fatal().Err(err).Msg("Failed to setup logging")
-		}
-
-		// Initialize database connection
-		dbConfig := db.Config{
-			Driver:          viper.GetString("app.database.driver"),
-			Host:            viper.GetString("app.database.host"),
-			Port:            viper.GetInt("app.database.port"),
-			Username:        viper.GetString("app.database.username"),
-			Password:        viper.GetString("app.database.password"),
-			Database:        viper.GetString("app.database.name"),
-			MaxOpenConns:    viper.GetInt("app.database.max_open_conns"),
-			MaxIdleConns:    viper.GetInt("app.database.max_idle_conns"),
-			ConnMaxLifetime: viper.GetInt("app.database.conn_max_lifetime"),
-			DataSource:      viper.GetString("app.database.datasource"),
-		}
-
-		conn, err := db.NewConnection(dbConfig)
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to connect to database")
-		}
+		mgr, conn := openMigrationManager(configFile)
 		defer conn.Close()
 
-		// Create migration manager
-		mgr := migration.NewManager(conn.DB, conn.Driver)
-
-		// Register all migrations
-		for _, m := range migration.GetAll() {
-			mgr.Register(m)
+		// Roll back migrations
+		var err error
+		if downTo != "" {
+			err = mgr.DownTo(downTo)
+		} else {
+			err = mgr.DownSteps(downSteps)
 		}
-
-		// Roll back migration
-		if err := mgr.Down(); err != nil {
+		if err != nil {
 			log.Fatal().Err(err).Msg("Failed to roll back migration")
 		}
 
@@ -122,47 +130,50 @@ var migrateDownCmd = &cobra.Command{
 	},
 }
 
-var migrateStatusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Show migration status",
+var migrateRedoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Roll back the last migration and apply it again",
 	Run: func(cmd *cobra.Command, _ []string) {
 		configFile, _ := cmd.Flags().GetString("config")
 
-		if err := core.Load(configFile); err != nil {
-			log.Fatal().Err(err).Msg("Failed to load configuration")
-		}
+		mgr, conn := openMigrationManager(configFile)
+		defer conn.Close()
 
-		if err := core.SetupLogging(); err != nil {
-			log.Fatal().Err(err).Msg("Failed to setup logging")
+		if err := mgr.Redo(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to redo migration")
 		}
 
-		// Initialize database connection
-		dbConfig := db.Config{
-			Driver:          viper.GetString("app.database.driver"),
-			Host:            viper.GetString("app.database.host"),
-			Port:            viper.GetInt("app.database.port"),
-			Username:        viper.GetString("app.database.username"),
-			Password:        viper.GetString("app.database.password"),
-			Database:        viper.GetString("app.database.name"),
-			MaxOpenConns:    viper.GetInt("app.database.max_open_conns"),
-			MaxIdleConns:    viper.GetInt("app.database.max_idle_conns"),
-			ConnMaxLifetime: viper.GetInt("app.database.conn_max_lifetime"),
-			DataSource:      viper.GetString("app.database.datasource"),
-		}
+		log.Info().Msg("Redo completed successfully")
+	},
+}
 
-		conn, err := db.NewConnection(dbConfig)
-		if err != nil {
-			log.Fatal().Err(err).Msg("Failed to connect to database")
-		}
-		defer conn.Close()
+var migrateBaselineCmd = &cobra.Command{
+	Use:   "baseline <version>",
+	Short: "Mark the migrations up to a version as applied without running them",
+	Long: `Mark the migrations up to and including a version as applied without
+running them, to adopt an existing database whose schema matches that version.
+Later migrations stay pending.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configFile, _ := cmd.Flags().GetString("config")
 
-		// Create migration manager
-		mgr := migration.NewManager(conn.DB, conn.Driver)
+		mgr, conn := openMigrationManager(configFile)
+		defer conn.Close()
 
-		// Register all migrations
-		for _, m := range migration.GetAll() {
-			mgr.Register(m)
+		if err := mgr.Baseline(args[0]); err != nil {
+			log.Fatal().Err(err).Msg("Failed to baseline database")
 		}
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show migration status",
+	Run: func(cmd *cobra.Command, _ []string) {
+		configFile, _ := cmd.Flags().GetString("config")
+
+		mgr, conn := openMigrationManager(configFile)
+		defer conn.Close()
 
 		// Show status
 		if err := mgr.Status(); err != nil {
@@ -171,34 +182,74 @@ var migrateStatusCmd = &cobra.Command{
 	},
 }
 
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create the SQL files of a new migration",
+	Long: `Create the up and down SQL files of a new migration for every database
+driver. The files are embedded in the binary on the next build.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		paths, err := migration.Create(migrationsDir, args[0])
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(migrateCmd)
 	migrateCmd.AddCommand(migrateUpCmd)
 	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateRedoCmd)
+	migrateCmd.AddCommand(migrateBaselineCmd)
 	migrateCmd.AddCommand(migrateStatusCmd)
-
-	migrateUpCmd.Flags().StringVarP(
-		&config,
-		"config",
-		"c",
-		"config.prod.yml",
-		"Absolute path to config file (required)",
+	migrateCmd.AddCommand(migrateCreateCmd)
+
+	for _, cmd := range []*cobra.Command{migrateUpCmd, migrateDownCmd, migrateRedoCmd, migrateBaselineCmd, migrateStatusCmd} {
+		cmd.Flags().StringVarP(
+			&config,
+			"config",
+			"c",
+			"config.prod.yml",
+			"Absolute path to config file (required)",
+		)
+		cmd.MarkFlagRequired("config")
+	}
+
+	migrateUpCmd.Flags().BoolVar(
+		&allowDrift,
+		"allow-drift",
+		false,
+		"Run although applied migrations were modified or removed, or pending ones are out of order",
+	)
+	migrateUpCmd.Flags().StringVar(
+		&upTo,
+		"to",
+		"",
+		"Only run the pending migrations up to and including this version",
+	)
+	migrateDownCmd.Flags().IntVar(
+		&downSteps,
+		"steps",
+		1,
+		"Number of migrations to roll back",
 	)
-	migrateUpCmd.MarkFlagRequired("config")
-	migrateDownCmd.Flags().StringVarP(
-		&config,
-		"config",
-		"c",
-		"config.prod.yml",
-		"Absolute path to config file (required)",
+	migrateDownCmd.Flags().StringVar(
+		&downTo,
+		"to",
+		"",
+		"Roll back the migrations applied after this version, which stays applied",
 	)
-	migrateDownCmd.MarkFlagRequired("config")
-	migrateStatusCmd.Flags().StringVarP(
-		&config,
-		"config",
-		"c",
-		"config.prod.yml",
-		"Absolute path to config file (required)",
+	migrateDownCmd.MarkFlagsMutuallyExclusive("steps", "to")
+	migrateCreateCmd.Flags().StringVarP(
+		&migrationsDir,
+		"dir",
+		"d",
+		migration.Dir,
+		"Existing directory to write the migration files to",
 	)
-	migrateStatusCmd.MarkFlagRequired("config")
 }
@@ -0,0 +1,251 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"os"
+
+	"github.com/clivern/zewi/core"
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// optionsFile is the options document to export to or import from
+	optionsFile string
+	// optionsNamespace is the namespace to export from or import into
+	optionsNamespace string
+	// importMode is merge or replace
+	importMode string
+	// importDryRun reports the changes of an import without applying them
+	importDryRun bool
+	// exportReveal exports the decrypted values of secret options
+	exportReveal bool
+)
+
+var optionsCmd = &cobra.Command{
+	Use:   "options",
+	Short: "Option management commands",
+	Long:  `Export and import options and rotate the keys encrypting secret options`,
+}
+
+// openOptionRepository loads the configuration and returns an option
+// repository scoped to the requested namespace
+func openOptionRepository(configFile string) *db.OptionRepository {
+	if err := core.Load(configFile); err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	if err := core.SetupLogging(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to setup logging")
+	}
+
+	if !service.IsValidKey(optionsNamespace) {
+		log.Fatal().Str("namespace", optionsNamespace).Msg("Invalid namespace")
+	}
+
+	if err := core.InitDatabaseAPI(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+
+	return db.NewOptionRepository(db.GetDB()).WithNamespace(optionsNamespace)
+}
+
+var optionsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the options of a namespace to a JSON or YAML file",
+	Run: func(cmd *cobra.Command, _ []string) {
+		configFile, _ := cmd.Flags().GetString("config")
+
+		repo := openOptionRepository(configFile)
+		defer db.CloseDB()
+
+		options, err := repo.List()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to list options")
+		}
+
+		values := make(map[string]string, len(options))
+		var secrets []string
+		for _, option := range options {
+			values[option.Key] = option.DisplayValue(exportReveal)
+			if option.Secret {
+				secrets = append(secrets, option.Key)
+			}
+		}
+
+		data, err := service.EncodeOptions(service.FormatFromPath(optionsFile), repo.Namespace(), values, secrets)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to encode options")
+		}
+
+		if err := os.WriteFile(optionsFile, data, 0o644); err != nil {
+			log.Fatal().Err(err).Msg("Failed to write options file")
+		}
+
+		log.Info().
+			Str("namespace", repo.Namespace()).
+			Str("file", optionsFile).
+			Int("count", len(values)).
+			Msg("Options exported successfully")
+	},
+}
+
+var optionsImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import options into a namespace from a JSON or YAML file",
+	Run: func(cmd *cobra.Command, _ []string) {
+		configFile, _ := cmd.Flags().GetString("config")
+
+		if importMode != db.ImportModeMerge && importMode != db.ImportModeReplace {
+			log.Fatal().Str("mode", importMode).Msg("Invalid mode, expected merge or replace")
+		}
+
+		data, err := os.ReadFile(optionsFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read options file")
+		}
+
+		values, secrets, err := service.DecodeOptions(service.FormatFromPath(optionsFile), data)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to decode options file")
+		}
+
+		repo := openOptionRepository(configFile)
+		defer db.CloseDB()
+
+		report, err := repo.Import(values, secrets, importMode, importDryRun)
+
+		var violation *db.SchemaViolationError
+		if errors.As(err, &violation) {
+			for _, v := range violation.Violations {
+				log.Error().
+					Str("key", violation.Key).
+					Str("path", v.Path).
+					Msg(v.Message)
+			}
+			log.Fatal().Err(err).Msg("Failed to import options")
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to import options")
+		}
+
+		for _, change := range report.Changes {
+			event := log.Info().Str("key", change.Key)
+			if change.Before != nil {
+				event = event.Str("before", *change.Before)
+			}
+			if change.After != nil {
+				event = event.Str("after", *change.After)
+			}
+
+			switch change.Action {
+			case db.RevisionActionCreate:
+				event.Msg("Created")
+			case db.RevisionActionUpdate:
+				event.Msg("Updated")
+			default:
+				event.Msg("Deleted")
+			}
+		}
+
+		log.Info().
+			Str("namespace", repo.Namespace()).
+			Str("mode", importMode).
+			Bool("dry_run", importDryRun).
+			Int("created", len(report.Created)).
+			Int("updated", len(report.Updated)).
+			Int("deleted", len(report.Deleted)).
+			Int("unchanged", len(report.Unchanged)).
+			Msg("Options imported successfully")
+	},
+}
+
+var optionsRotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Wrap the data keys of secret options with the active encryption key",
+	Run: func(cmd *cobra.Command, _ []string) {
+		configFile, _ := cmd.Flags().GetString("config")
+
+		repo := openOptionRepository(configFile)
+		defer db.CloseDB()
+
+		rotated, err := repo.RotateKeys()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to rotate encryption keys")
+		}
+
+		log.Info().
+			Str("key_id", db.GetKeyring().ActiveKeyID()).
+			Int("count", rotated).
+			Msg("Encryption keys rotated successfully")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(optionsCmd)
+	optionsCmd.AddCommand(optionsExportCmd)
+	optionsCmd.AddCommand(optionsImportCmd)
+	optionsCmd.AddCommand(optionsRotateKeyCmd)
+
+	optionsRotateKeyCmd.Flags().StringVarP(
+		&config,
+		"config",
+		"c",
+		"config.prod.yml",
+		"Absolute path to config file (required)",
+	)
+	optionsRotateKeyCmd.MarkFlagRequired("config")
+
+	for _, cmd := range []*cobra.Command{optionsExportCmd, optionsImportCmd} {
+		cmd.Flags().StringVarP(
+			&config,
+			"config",
+			"c",
+			"config.prod.yml",
+			"Absolute path to config file (required)",
+		)
+		cmd.MarkFlagRequired("config")
+		cmd.Flags().StringVarP(
+			&optionsFile,
+			"file",
+			"f",
+			"",
+			"Path to the JSON or YAML options file (required)",
+		)
+		cmd.MarkFlagRequired("file")
+		cmd.Flags().StringVarP(
+			&optionsNamespace,
+			"namespace",
+			"n",
+			db.DefaultNamespace,
+			"Namespace of the options",
+		)
+	}
+
+	optionsImportCmd.Flags().StringVarP(
+		&importMode,
+		"mode",
+		"m",
+		db.ImportModeMerge,
+		"Import mode, merge or replace",
+	)
+	optionsExportCmd.Flags().BoolVar(
+		&exportReveal,
+		"reveal",
+		false,
+		"Export the decrypted values of secret options instead of masking them",
+	)
+	optionsImportCmd.Flags().BoolVar(
+		&importDryRun,
+		"dry-run",
+		false,
+		"Report the changes without applying them",
+	)
+}
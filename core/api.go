@@ -12,6 +12,8 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -31,34 +33,157 @@ func SetupAPI(Static embed.FS) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(chimiddleware.Recoverer)
-	if viper.GetInt("app.timeout") > 0 {
-		timeout := time.Duration(viper.GetInt("app.timeout")) * time.Second
-		r.Use(chimiddleware.Timeout(timeout))
-	}
+	r.Use(middleware.RequestID)
 	r.Use(middleware.CORS)
 	r.Use(middleware.PrometheusMiddleware)
 	r.Use(middleware.Logger)
 
-	// Routes
-	r.Get("/favicon.ico", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusNoContent)
+	// Regular requests are bounded by the configured timeout while streams
+	// are long lived and bypass it
+	timeout := func(next http.Handler) http.Handler { return next }
+	if viper.GetInt("app.timeout") > 0 {
+		timeout = chimiddleware.Timeout(time.Duration(viper.GetInt("app.timeout")) * time.Second)
+	}
+
+	// API endpoints require basic auth once credentials are configured, the
+	// authenticated user is recorded as the actor of audit events
+	auth := func(next http.Handler) http.Handler { return next }
+	if viper.GetString("app.auth.username") != "" {
+		auth = middleware.BasicAuth(
+			viper.GetString("app.auth.username"),
+			viper.GetString("app.auth.secret"),
+		)
+	}
+
+	// WebSocket handshakes from other origins must be allowed explicitly
+	api.SetWebSocketOrigins(strings.FieldsFunc(viper.GetString("app.websocket.allowed_origins"), func(r rune) bool {
+		return r == ',' || r == ' '
+	}))
+
+	// Stream endpoints
+	r.With(auth).Get("/api/v1/state/stream", api.StreamStateAction)
+	r.With(auth).Get("/api/v1/ws", api.WebSocketAction)
+
+	// Option endpoints, scoped to the default namespace or to an explicit one
+	optionRoutes := func(r chi.Router) {
+		r.Use(auth)
+		r.Get("/_stream", api.StreamOptionsAction)
+		r.Get("/{key}/stream", api.StreamOptionAction)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Audit)
+			r.Use(timeout)
+			r.Get("/", api.ListOptionsAction)
+			r.Get("/export", api.ExportOptionsAction)
+			r.Post("/import", api.ImportOptionsAction)
+			r.Post("/batch", api.BatchOptionsAction)
+			r.Get("/trash", api.TrashOptionsAction)
+			r.Get("/{key}", api.GetOptionAction)
+			r.Put("/{key}", api.PutOptionAction)
+			r.Patch("/{key}", api.PatchOptionAction)
+			r.Delete("/{key}", api.DeleteOptionAction)
+			r.Get("/{key}/history", api.OptionHistoryAction)
+			r.Post("/{key}/revert", api.RevertOptionAction)
+			r.Post("/{key}/restore", api.RestoreOptionAction)
+		})
+	}
+	r.Route("/api/v1/options", optionRoutes)
+	r.Route("/api/v1/namespaces/{namespace}/options", optionRoutes)
+
+	// Schema endpoints, validating option values under a key prefix
+	schemaRoutes := func(r chi.Router) {
+		r.Use(auth)
+		r.Use(middleware.Audit)
+		r.Use(timeout)
+		r.Get("/", api.ListSchemasAction)
+		r.Get("/{prefix}", api.GetSchemaAction)
+		r.Put("/{prefix}", api.PutSchemaAction)
+		r.Delete("/{prefix}", api.DeleteSchemaAction)
+	}
+	r.Route("/api/v1/schemas", schemaRoutes)
+	r.Route("/api/v1/namespaces/{namespace}/schemas", schemaRoutes)
+
+	// Feature flag endpoints, flags are options under the flag prefix
+	flagRoutes := func(r chi.Router) {
+		r.Use(auth)
+
+		// Evaluations do not change anything and are not audited
+		r.With(timeout).Post("/evaluate", api.EvaluateFlagsAction)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Audit)
+			r.Use(timeout)
+			r.Get("/", api.ListFlagsAction)
+			r.Get("/{flag}", api.GetFlagAction)
+			r.Put("/{flag}", api.PutFlagAction)
+			r.Delete("/{flag}", api.DeleteFlagAction)
+		})
+	}
+	r.Route("/api/v1/flags", flagRoutes)
+	r.Route("/api/v1/namespaces/{namespace}/flags", flagRoutes)
+
+	// Lock endpoints, leases giving batch jobs mutual exclusion
+	r.Route("/api/v1/locks", func(r chi.Router) {
+		r.Use(auth)
+		r.Use(timeout)
+		r.Get("/", api.ListLocksAction)
+		r.Get("/{name}", api.GetLockAction)
+		r.Post("/{name}/validate", api.ValidateLockAction)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Audit)
+			r.Post("/{name}/acquire", api.AcquireLockAction)
+			r.Post("/{name}/renew", api.RenewLockAction)
+			r.Post("/{name}/release", api.ReleaseLockAction)
+		})
 	})
-	r.Get("/_health", api.HealthAction)
-	r.Get("/_ready", api.ReadyAction)
-	r.With(middleware.BasicAuth(
-		viper.GetString("app.metrics.username"),
-		viper.GetString("app.metrics.secret"),
-	)).Get("/_metrics", promhttp.Handler().ServeHTTP)
 
-	// State endpoints
-	r.Get("/api/v1/state", api.GetStateAction)
-	r.Put("/api/v1/state", api.UpdateStateAction)
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Audit)
+		r.Use(timeout)
+
+		// Routes
+		r.Get("/favicon.ico", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		r.Get("/_health", api.HealthAction)
+		r.Get("/_ready", api.ReadyAction)
+		r.With(middleware.BasicAuth(
+			viper.GetString("app.metrics.username"),
+			viper.GetString("app.metrics.secret"),
+		)).Get("/_metrics", promhttp.Handler().ServeHTTP)
+		r.With(middleware.BasicAuth(
+			viper.GetString("app.metrics.username"),
+			viper.GetString("app.metrics.secret"),
+		)).Get("/_leader", api.LeaderAction)
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth)
+
+			// State endpoints
+			r.Get("/api/v1/state", api.GetStateAction)
+			r.Put("/api/v1/state", api.UpdateStateAction)
+			r.Patch("/api/v1/state", api.PatchStateAction)
+
+			// Namespace endpoints
+			r.Get("/api/v1/namespaces", api.ListNamespacesAction)
+			r.Delete("/api/v1/namespaces/{namespace}", api.DeleteNamespaceAction)
+
+			// Audit endpoints
+			r.Get("/api/v1/audit", api.ListAuditEventsAction)
+		})
+	})
 
 	return r
 }
 
-// InitDatabaseAPI initializes the database connection from configuration
+// InitDatabaseAPI initializes the database connection and the encryption
+// keys from configuration
 func InitDatabaseAPI() error {
+	if err := InitKeyring(); err != nil {
+		return err
+	}
+
 	dbConfig := db.Config{
 		Driver:          viper.GetString("app.database.driver"),
 		Host:            viper.GetString("app.database.host"),
@@ -87,11 +212,49 @@ func RunAPI(handler http.Handler) error {
 		}
 	}()
 
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Background work runs on the elected leader replica only
+	elector := db.NewElector(db.GetDB(), leaderIdentity(), leaderLeaseTTL())
+	db.SetElector(elector)
+
+	election, stopElection := context.WithCancel(workers)
+	electionDone := make(chan struct{})
+
+	go func() {
+		defer close(electionDone)
+		elector.Run(election, RunLeaderWork)
+	}()
+
+	// Hand off leadership before the database connection is closed
+	resign := sync.OnceFunc(func() {
+		stopElection()
+		<-electionDone
+	})
+	defer resign()
+
+	db.InitCache(
+		viper.GetInt("app.options.cache.size"),
+		time.Duration(viper.GetInt("app.options.cache.ttl"))*time.Second,
+	)
+
+	go db.WatchCache(workers)
+
+	go func() {
+		if err := db.ListenChanges(workers); err != nil {
+			log.Error().Err(err).Msg("Change feed listener stopped")
+		}
+	}()
+
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", strconv.Itoa(viper.GetInt("app.port"))),
 		Handler: handler,
 	}
 
+	// Finish open streams so the shutdown does not wait on them
+	srv.RegisterOnShutdown(db.CloseChanges)
+
 	serverErrors := make(chan error, 1)
 
 	go func() {
@@ -132,6 +295,9 @@ func RunAPI(handler http.Handler) error {
 		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 
+		// Let another replica take over the background work right away
+		resign()
+
 		log.Info().
 			Dur("timeout", shutdownTimeout).
 			Msg("Gracefully shutting down server")
@@ -0,0 +1,43 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/service"
+
+	"github.com/spf13/viper"
+)
+
+// InitKeyring loads the keys encrypting secret option values from the
+// configuration or the configured key file. Encryption stays disabled if no
+// keys are configured.
+func InitKeyring() error {
+	spec := viper.GetString("app.encryption.keys")
+
+	if path := viper.GetString("app.encryption.key_file"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error while reading key file [%s]: %w", path, err)
+		}
+		spec = string(data)
+	}
+
+	if spec == "" {
+		db.SetKeyring(nil)
+		return nil
+	}
+
+	keyring, err := service.ParseKeyring(spec)
+	if err != nil {
+		return fmt.Errorf("invalid encryption keys: %w", err)
+	}
+
+	db.SetKeyring(keyring)
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+// defaultLeaderLeaseTTL is the leader lease lifetime used when not configured
+const defaultLeaderLeaseTTL = 15 * time.Second
+
+// defaultTrashSweepInterval is the interval between trash purges used when
+// not configured
+const defaultTrashSweepInterval = time.Hour
+
+// leaderIdentity identifies the API replica in leader election. The random
+// suffix tells apart processes sharing a hostname.
+func leaderIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8])
+}
+
+// leaderLeaseTTL returns the configured leader lease lifetime
+func leaderLeaseTTL() time.Duration {
+	ttl := time.Duration(viper.GetInt("app.leader.lease_ttl")) * time.Second
+	if ttl <= 0 {
+		return defaultLeaderLeaseTTL
+	}
+	return ttl
+}
+
+// trashSweepInterval returns the configured interval between trash purges
+func trashSweepInterval() time.Duration {
+	interval := time.Duration(viper.GetInt("app.options.trash_sweep_interval")) * time.Second
+	if interval <= 0 {
+		return defaultTrashSweepInterval
+	}
+	return interval
+}
+
+// RunLeaderWork runs the background work that must run on exactly one API
+// replica until the context is cancelled
+func RunLeaderWork(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	if viper.GetInt("app.options.sweep_interval") > 0 {
+		interval := time.Duration(viper.GetInt("app.options.sweep_interval")) * time.Second
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RunExpirySweeper(ctx, interval)
+		}()
+	}
+
+	if viper.GetInt("app.options.trash_retention") > 0 {
+		retention := time.Duration(viper.GetInt("app.options.trash_retention")) * time.Second
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RunTrashSweeper(ctx, trashSweepInterval(), retention)
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/clivern/zewi/db"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+var optionsExpiredTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "zewi_options_expired_total",
+		Help: "Total number of expired options purged by the sweeper",
+	},
+)
+
+var optionsTrashPurgedTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "zewi_options_trash_purged_total",
+		Help: "Total number of deleted options purged from the trash by the sweeper",
+	},
+)
+
+// RunExpirySweeper periodically purges expired options until the context is cancelled
+func RunExpirySweeper(ctx context.Context, interval time.Duration) {
+	log.Info().
+		Dur("interval", interval).
+		Msg("Starting expired options sweeper")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Expired options sweeper stopped")
+			return
+		case <-ticker.C:
+			database := db.GetDB()
+			if database == nil {
+				continue
+			}
+
+			purged, err := db.NewOptionRepository(database).PurgeExpired()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to purge expired options")
+				continue
+			}
+
+			if purged > 0 {
+				optionsExpiredTotal.Add(float64(purged))
+				log.Info().Int("count", purged).Msg("Purged expired options")
+			}
+		}
+	}
+}
+
+// RunTrashSweeper periodically purges options that stayed in the trash longer
+// than the retention until the context is cancelled
+func RunTrashSweeper(ctx context.Context, interval, retention time.Duration) {
+	log.Info().
+		Dur("interval", interval).
+		Dur("retention", retention).
+		Msg("Starting trash sweeper")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Trash sweeper stopped")
+			return
+		case <-ticker.C:
+			database := db.GetDB()
+			if database == nil {
+				continue
+			}
+
+			purged, err := db.NewOptionRepository(database).PurgeDeleted(time.Now().Add(-retention))
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to purge trash")
+				continue
+			}
+
+			if purged > 0 {
+				optionsTrashPurgedTotal.Add(float64(purged))
+				log.Info().Int("count", purged).Msg("Purged options from trash")
+			}
+		}
+	}
+}
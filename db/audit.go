@@ -0,0 +1,300 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clivern/zewi/service"
+)
+
+const (
+	// AuditResultSuccess marks a request that completed with a 1xx, 2xx or 3xx status
+	AuditResultSuccess = "success"
+	// AuditResultFailure marks a request that completed with a 4xx or 5xx status
+	AuditResultFailure = "failure"
+)
+
+// AuditEvent records who changed what, when and from where.
+type AuditEvent struct {
+	ID        int64
+	RequestID string
+	Actor     string
+	RemoteIP  string
+	Method    string
+	Path      string
+	Namespace string
+	// Key is the option key the request targeted, if any
+	Key string
+	// OldValueHash and NewValueHash are the SHA-256 digests of the option
+	// value before and after the request, empty if the option did not exist
+	// or is secret
+	OldValueHash string
+	NewValueHash string
+	Status       int
+	Result       string
+	CreatedAt    time.Time
+}
+
+// AuditFilter selects audit events. Empty fields match every event.
+type AuditFilter struct {
+	Actor     string
+	RequestID string
+	Method    string
+	Namespace string
+	Key       string
+	Result    string
+	Since     *time.Time
+	Until     *time.Time
+	// BeforeID only keeps events older than the given event ID.
+	BeforeID int64
+	// Limit is the maximum number of events returned, zero means no limit.
+	Limit int
+}
+
+// auditColumns lists the columns scanned by scanAuditEvent.
+const auditColumns = `id, request_id, actor, remote_ip, method, path, namespace, option_key,
+	old_value_hash, new_value_hash, status, result, created_at`
+
+// scanAuditEvent scans an audit event row selected with auditColumns.
+func scanAuditEvent(row interface{ Scan(...interface{}) error }) (*AuditEvent, error) {
+	event := &AuditEvent{}
+	var oldHash, newHash sql.NullString
+
+	err := row.Scan(
+		&event.ID,
+		&event.RequestID,
+		&event.Actor,
+		&event.RemoteIP,
+		&event.Method,
+		&event.Path,
+		&event.Namespace,
+		&event.Key,
+		&oldHash,
+		&newHash,
+		&event.Status,
+		&event.Result,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.OldValueHash = oldHash.String
+	event.NewValueHash = newHash.String
+	event.CreatedAt = event.CreatedAt.UTC()
+	return event, nil
+}
+
+// AuditRepository handles database operations for audit events.
+type AuditRepository struct {
+	db     querier
+	driver string
+}
+
+// NewAuditRepository creates a new audit repository.
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		db:     db,
+		driver: GetDriver(),
+	}
+}
+
+// Record stores an audit event and sets its ID and creation time.
+func (r *AuditRepository) Record(event *AuditEvent) error {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `INSERT INTO audit_events (request_id, actor, remote_ip, method, path, namespace, option_key,
+			old_value_hash, new_value_hash, status, result, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`
+	} else {
+		query = `INSERT INTO audit_events (request_id, actor, remote_ip, method, path, namespace, option_key,
+			old_value_hash, new_value_hash, status, result, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`
+	}
+
+	event.CreatedAt = time.Now().UTC()
+
+	return r.db.QueryRow(
+		query,
+		event.RequestID,
+		event.Actor,
+		event.RemoteIP,
+		event.Method,
+		event.Path,
+		event.Namespace,
+		event.Key,
+		nullString(event.OldValueHash),
+		nullString(event.NewValueHash),
+		event.Status,
+		event.Result,
+		event.CreatedAt,
+	).Scan(&event.ID)
+}
+
+// Find retrieves the audit events matching the filter, newest first.
+func (r *AuditRepository) Find(filter AuditFilter) ([]*AuditEvent, error) {
+	postgres := r.driver == "postgres" || r.driver == "postgresql"
+
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		if postgres {
+			return fmt.Sprintf("$%d", len(args))
+		}
+		return "?"
+	}
+
+	conditions := []string{"1 = 1"}
+	for _, field := range []struct{ column, value string }{
+		{"actor", filter.Actor},
+		{"request_id", filter.RequestID},
+		{"method", filter.Method},
+		{"namespace", filter.Namespace},
+		{"option_key", filter.Key},
+		{"result", filter.Result},
+	} {
+		if field.value != "" {
+			conditions = append(conditions, field.column+" = "+arg(field.value))
+		}
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= "+arg(filter.Since.UTC()))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < "+arg(filter.Until.UTC()))
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < "+arg(filter.BeforeID))
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_events WHERE " + strings.Join(conditions, " AND ") + " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// nullString converts an optional string into a value suitable for a
+// nullable column.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// AuditChange is an option change collected by an AuditTrail. The hashes are
+// empty if the option did not exist or is secret.
+type AuditChange struct {
+	Namespace    string
+	Key          string
+	OldValueHash string
+	NewValueHash string
+}
+
+// AuditTrail collects the option changes committed by the repositories it is
+// attached to, so a request changing many options records every one of them.
+type AuditTrail struct {
+	mu      sync.Mutex
+	changes []AuditChange
+}
+
+// NewAuditTrail creates an empty audit trail.
+func NewAuditTrail() *AuditTrail {
+	return &AuditTrail{}
+}
+
+// Changes returns the collected changes in commit order, none for a nil trail.
+func (t *AuditTrail) Changes() []AuditChange {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]AuditChange(nil), t.changes...)
+}
+
+// add collects a committed change.
+func (t *AuditTrail) add(change Change) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.changes = append(t.changes, AuditChange{
+		Namespace:    change.Namespace,
+		Key:          change.Key,
+		OldValueHash: change.oldValueHash,
+		NewValueHash: change.newValueHash,
+	})
+}
+
+// WithAuditTrail returns a copy of the repository that adds the changes it
+// commits to the trail. A nil trail collects nothing.
+func (r *OptionRepository) WithAuditTrail(trail *AuditTrail) *OptionRepository {
+	repo := r.WithNamespace(r.namespace)
+	repo.trail = trail
+	return repo
+}
+
+// auditValueHash returns the hash of the value an option had before the
+// change being recorded, from its latest revision. It is empty if the option
+// did not exist or is secret.
+func (r *OptionRepository) auditValueHash(key string) (string, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT value, action, secret FROM option_revisions
+		WHERE namespace = $1 AND option_key = $2 ORDER BY id DESC LIMIT 1`
+	} else {
+		query = `SELECT value, action, secret FROM option_revisions
+		WHERE namespace = ? AND option_key = ? ORDER BY id DESC LIMIT 1`
+	}
+
+	var value sql.NullString
+	var action string
+	var secret bool
+	err := r.db.QueryRow(query, r.namespace, key).Scan(&value, &action, &secret)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return sealedValueHash(&sealedValue{Value: value.String, Secret: secret}, action), nil
+}
+
+// sealedValueHash returns the hash of a value as stored, empty for a delete
+// or a secret value. Hashing secret values would let them be brute-forced
+// from the audit log.
+func sealedValueHash(value *sealedValue, action string) string {
+	if value == nil || value.Secret || action == RevisionActionDelete {
+		return ""
+	}
+	return service.HashValue(value.Value)
+}
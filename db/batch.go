@@ -0,0 +1,156 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// BatchOpSet creates or updates an option.
+	BatchOpSet = "set"
+	// BatchOpDelete removes an option, a missing option is left alone.
+	BatchOpDelete = "delete"
+	// BatchOpCheck only evaluates the preconditions of the operation.
+	BatchOpCheck = "check"
+)
+
+// ErrPreconditionFailed is returned when a batch operation precondition does not hold.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// BatchOperation is a single operation of a batch. ExpectedVersion, if not
+// zero, and Exists, if not nil, are preconditions on the current option.
+type BatchOperation struct {
+	Op              string
+	Key             string
+	Value           string
+	ExpiresAt       *time.Time
+	ExpectedVersion int64
+	Exists          *bool
+}
+
+// BatchResult is the outcome of a batch operation. Option is the stored
+// option for set and the current option, if any, for check.
+type BatchResult struct {
+	Op      string
+	Key     string
+	Option  *Option
+	Deleted bool
+}
+
+// BatchError is returned when a batch operation fails. It wraps the cause,
+// such as ErrPreconditionFailed or a *SchemaViolationError.
+type BatchError struct {
+	Index int
+	Key   string
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d on option %s: %s", e.Index, e.Key, e.Err)
+}
+
+// Unwrap returns the cause of the failure.
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch applies the operations in order within a single transaction. If any
+// precondition fails or any operation errors, nothing is applied.
+func (r *OptionRepository) Batch(operations []BatchOperation) ([]*BatchResult, error) {
+	var results []*BatchResult
+
+	err := r.Transaction(func(tx *OptionRepository) error {
+		results = make([]*BatchResult, 0, len(operations))
+
+		for i, operation := range operations {
+			result, err := tx.applyBatchOperation(operation)
+			if err != nil {
+				return &BatchError{Index: i, Key: operation.Key, Err: err}
+			}
+			results = append(results, result)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// applyBatchOperation checks the preconditions of an operation and applies it.
+func (r *OptionRepository) applyBatchOperation(operation BatchOperation) (*BatchResult, error) {
+	current, err := r.Get(operation.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if operation.Exists != nil && *operation.Exists && current == nil {
+		return nil, fmt.Errorf("%w: option does not exist", ErrPreconditionFailed)
+	}
+	if operation.Exists != nil && !*operation.Exists && current != nil {
+		return nil, fmt.Errorf("%w: option already exists", ErrPreconditionFailed)
+	}
+	if operation.ExpectedVersion > 0 {
+		if current == nil {
+			return nil, fmt.Errorf("%w: option does not exist", ErrPreconditionFailed)
+		}
+		if current.Version != operation.ExpectedVersion {
+			return nil, fmt.Errorf(
+				"%w: expected version %d, found %d",
+				ErrPreconditionFailed,
+				operation.ExpectedVersion,
+				current.Version,
+			)
+		}
+	}
+
+	result := &BatchResult{
+		Op:  operation.Op,
+		Key: operation.Key,
+	}
+
+	switch operation.Op {
+	case BatchOpCheck:
+		result.Option = current
+	case BatchOpDelete:
+		if current != nil {
+			if err := r.Delete(operation.Key); err != nil {
+				return nil, err
+			}
+			result.Deleted = true
+		}
+	case BatchOpSet:
+		if err := r.Schemas().Validate(operation.Key, operation.Value); err != nil {
+			return nil, err
+		}
+
+		if current == nil {
+			result.Option, _, err = r.Upsert(operation.Key, operation.Value, operation.ExpiresAt)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		// Swap against the version read above so a concurrent write fails the batch
+		result.Option, err = r.CompareAndSwap(operation.Key, operation.Value, operation.ExpiresAt, current.Version)
+		if err != nil {
+			return nil, err
+		}
+		if result.Option == nil {
+			return nil, fmt.Errorf("%w: option was modified concurrently", ErrPreconditionFailed)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported operation: %s", operation.Op)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitBatch(t *testing.T) {
+	t.Run("Applies every operation in order", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		current, _, err := repo.Upsert("app.name", `"zewi"`, nil)
+		require.NoError(t, err)
+		_, _, err = repo.Upsert("app.old", `1`, nil)
+		require.NoError(t, err)
+
+		exists := false
+		results, err := repo.Batch([]BatchOperation{
+			{Op: BatchOpSet, Key: "app.name", Value: `"zewi 2"`, ExpectedVersion: current.Version},
+			{Op: BatchOpSet, Key: "app.new", Value: `true`, Exists: &exists},
+			{Op: BatchOpDelete, Key: "app.old"},
+			{Op: BatchOpCheck, Key: "app.new"},
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+		assert.Equal(t, `"zewi 2"`, results[0].Option.Value)
+		assert.True(t, results[2].Deleted)
+		assert.Equal(t, `true`, results[3].Option.Value)
+
+		option, err := repo.Get("app.old")
+		assert.NoError(t, err)
+		assert.Nil(t, option)
+	})
+
+	t.Run("Rolls back every operation when one fails", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		_, _, err := repo.Upsert("app.name", `"zewi"`, nil)
+		require.NoError(t, err)
+
+		_, err = repo.Batch([]BatchOperation{
+			{Op: BatchOpSet, Key: "app.name", Value: `"zewi 2"`},
+			{Op: BatchOpSet, Key: "app.new", Value: `true`},
+			{Op: BatchOpDelete, Key: "app.name"},
+			{Op: BatchOpSet, Key: "app.name", Value: `"zewi 3"`, ExpectedVersion: 1},
+		})
+
+		var batchErr *BatchError
+		require.True(t, errors.As(err, &batchErr))
+		assert.Equal(t, 3, batchErr.Index)
+		assert.True(t, errors.Is(err, ErrPreconditionFailed))
+
+		option, err := repo.Get("app.name")
+		assert.NoError(t, err)
+		assert.Equal(t, `"zewi"`, option.Value)
+		assert.Equal(t, int64(1), option.Version)
+
+		option, err = repo.Get("app.new")
+		assert.NoError(t, err)
+		assert.Nil(t, option)
+
+		revisions, err := repo.History("app.name", 10)
+		assert.NoError(t, err)
+		assert.Len(t, revisions, 1)
+	})
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clivern/zewi/service"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	optionCacheHitsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "zewi_option_cache_hits_total",
+			Help: "Total number of options read by key served from the cache",
+		},
+	)
+
+	optionCacheMissesTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "zewi_option_cache_misses_total",
+			Help: "Total number of options read by key missing from the cache",
+		},
+	)
+
+	optionCacheEvictionsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "zewi_option_cache_evictions_total",
+			Help: "Total number of cached options evicted to stay within the cache size",
+		},
+	)
+)
+
+// optionCache caches options read by key, including missing ones, outside of
+// transactions. Entries are invalidated when a change to the option is
+// committed locally or announced by another replica.
+type optionCache struct {
+	entries *service.Cache
+	ttl     time.Duration
+	// generation is bumped by every invalidation, so a read racing with a
+	// write does not cache the value it read before the write
+	generation atomic.Uint64
+}
+
+var (
+	// globalCache is the process wide option cache, nil if disabled
+	globalCache *optionCache
+	// cacheMu protects globalCache
+	cacheMu sync.RWMutex
+)
+
+// InitCache enables caching up to size options read by key for ttl. A zero
+// size or ttl disables the cache.
+func InitCache(size int, ttl time.Duration) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if size <= 0 || ttl <= 0 {
+		globalCache = nil
+		return
+	}
+
+	globalCache = &optionCache{
+		entries: service.NewCache(size),
+		ttl:     ttl,
+	}
+}
+
+// getCache returns the option cache, or nil if disabled.
+func getCache() *optionCache {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+
+	return globalCache
+}
+
+// WatchCache invalidates cached options changed by other replicas until the
+// context is cancelled or the change feed is closed. Changes are delivered
+// as hints, one missed by a full buffer stays cached until its TTL expires.
+func WatchCache(ctx context.Context) {
+	if getCache() == nil {
+		return
+	}
+
+	changes, unsubscribe := SubscribeChanges(1024)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			invalidateCache(change)
+		}
+	}
+}
+
+// invalidateCache removes the option of a change from the cache. The zero
+// Change purges the whole cache since changes may have been missed.
+func invalidateCache(change Change) {
+	cache := getCache()
+	if cache == nil {
+		return
+	}
+
+	cache.generation.Add(1)
+
+	if change == (Change{}) {
+		log.Debug().Msg("Purging option cache")
+		cache.entries.Purge()
+		return
+	}
+
+	cache.entries.Delete(cacheKey(change.Namespace, change.Key))
+}
+
+// cacheKey returns the cache key of an option
+func cacheKey(namespace, key string) string {
+	return namespace + "\x00" + key
+}
+
+// get returns a copy of the cached option, nil if cached as missing, and
+// whether it was cached.
+func (c *optionCache) get(namespace, key string) (*Option, bool) {
+	value, ok := c.entries.Get(cacheKey(namespace, key))
+	if !ok {
+		optionCacheMissesTotal.Inc()
+		return nil, false
+	}
+
+	optionCacheHitsTotal.Inc()
+
+	option := value.(*Option)
+	if option == nil {
+		return nil, true
+	}
+
+	clone := *option
+	return &clone, true
+}
+
+// set caches an option, or nil if missing, read while the cache was at the
+// given generation. Options are cached no longer than they live.
+func (c *optionCache) set(namespace, key string, option *Option, generation uint64) {
+	ttl := c.ttl
+	if option != nil && option.ExpiresAt != nil {
+		if remaining := time.Until(*option.ExpiresAt); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	if ttl <= 0 || c.generation.Load() != generation {
+		return
+	}
+
+	var value *Option
+	if option != nil {
+		clone := *option
+		value = &clone
+	}
+
+	if c.entries.Set(cacheKey(namespace, key), value, ttl) {
+		optionCacheEvictionsTotal.Inc()
+	}
+
+	// An invalidation may have run between the generation check and the set
+	if c.generation.Load() != generation {
+		c.entries.Delete(cacheKey(namespace, key))
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// changesChannel is the postgres NOTIFY channel carrying option changes.
+const changesChannel = "zewi_changes"
+
+// Change describes a committed option change. The zero Change is published
+// when changes may have been missed, so subscribers should catch up from the
+// revision history.
+type Change struct {
+	Revision  int64  `json:"revision"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Action    string `json:"action"`
+	// oldValueHash and newValueHash are collected by an audit trail
+	oldValueHash string
+	newValueHash string
+}
+
+// changeFeed fans out committed changes to in-process subscribers.
+type changeFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan Change]struct{}
+	closed      bool
+}
+
+// feed is the process wide change feed.
+var feed = &changeFeed{
+	subscribers: make(map[chan Change]struct{}),
+}
+
+// SubscribeChanges registers a subscriber to the change feed. Changes are
+// dropped for a subscriber whose buffer is full, so subscribers should treat
+// a change as a hint and read the revision history from their last seen
+// revision. The channel is closed when the feed is closed. The returned
+// function unsubscribes.
+func SubscribeChanges(buffer int) (<-chan Change, func()) {
+	ch := make(chan Change, buffer)
+
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	if feed.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	feed.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		feed.mu.Lock()
+		defer feed.mu.Unlock()
+
+		if _, ok := feed.subscribers[ch]; ok {
+			delete(feed.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// CloseChanges closes the channel of every subscriber so long lived readers
+// such as streams can finish during shutdown.
+func CloseChanges() {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	for ch := range feed.subscribers {
+		delete(feed.subscribers, ch)
+		close(ch)
+	}
+	feed.closed = true
+}
+
+// publishChange delivers a change to every subscriber without blocking.
+func publishChange(change Change) {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	for ch := range feed.subscribers {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
+
+// queueChange records a change made through the repository. Within a
+// transaction the change is held until commit, postgres additionally
+// notifies every listening replica once the transaction commits.
+func (r *OptionRepository) queueChange(change Change) error {
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		payload, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+
+		if _, err := r.db.Exec("SELECT pg_notify($1, $2)", changesChannel, string(payload)); err != nil {
+			return err
+		}
+	}
+
+	if r.changes != nil {
+		*r.changes = append(*r.changes, change)
+		return nil
+	}
+
+	r.commitChange(change)
+	return nil
+}
+
+// commitChange applies a committed change locally. The option cache is
+// invalidated right away so the writer reads its own write, while postgres
+// delivers the change to the feed through NOTIFY. The change is added to the
+// audit trail of the repository, if any.
+func (r *OptionRepository) commitChange(change Change) {
+	invalidateCache(change)
+
+	if r.trail != nil {
+		r.trail.add(change)
+	}
+
+	if r.driver != "postgres" && r.driver != "postgresql" {
+		publishChange(change)
+	}
+}
+
+// ListenChanges relays the changes committed by every API replica to the
+// local change feed using postgres LISTEN/NOTIFY. It blocks until the context
+// is cancelled. For sqlite it returns immediately since changes are published
+// in process.
+func ListenChanges(ctx context.Context) error {
+	mu.RLock()
+	conn := globalConnection
+	mu.RUnlock()
+
+	if conn == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	if conn.Driver != "postgres" && conn.Driver != "postgresql" {
+		return nil
+	}
+
+	listener := pq.NewListener(conn.dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Error().Err(err).Msg("Change feed listener error")
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(changesChannel); err != nil {
+		return fmt.Errorf("failed to listen for changes: %w", err)
+	}
+
+	log.Info().Str("channel", changesChannel).Msg("Listening for option changes")
+
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// A nil notification follows a reconnect, anything could have been missed
+			if notification == nil {
+				publishChange(Change{})
+				continue
+			}
+
+			var change Change
+			if err := json.Unmarshal([]byte(notification.Extra), &change); err != nil {
+				log.Error().Err(err).Msg("Invalid change notification")
+				continue
+			}
+			publishChange(change)
+		case <-ticker.C:
+			go listener.Ping()
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 type Connection struct {
 	DB     *sql.DB
 	Driver string
+	dsn    string
 }
 
 // Config holds database configuration
@@ -89,6 +90,7 @@ func NewConnection(config Config) (*Connection, error) {
 	return &Connection{
 		DB:     db,
 		Driver: config.Driver,
+		dsn:    dsn,
 	}, nil
 }
 
@@ -0,0 +1,52 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"strings"
+
+	"github.com/clivern/zewi/service"
+)
+
+// FlagPrefix is the key prefix of the options holding feature flag
+// definitions. Values written under it must be valid flags.
+const FlagPrefix = "flags."
+
+// FlagKey returns the key of the option holding a feature flag.
+func FlagKey(name string) string {
+	return FlagPrefix + name
+}
+
+// FlagName returns the name of the feature flag held by an option key.
+func FlagName(key string) string {
+	return strings.TrimPrefix(key, FlagPrefix)
+}
+
+// Flags retrieves the options holding the feature flags of the namespace
+// ordered by key.
+func (r *OptionRepository) Flags() ([]*Option, error) {
+	page, err := r.Find(ListOptions{Prefix: FlagPrefix, Sort: SortByKey})
+	if err != nil {
+		return nil, err
+	}
+	return page.Options, nil
+}
+
+// validateFlag checks the value of an option under FlagPrefix is a valid flag
+// definition. It returns a *SchemaViolationError if not.
+func (r *SchemaRepository) validateFlag(key, value string) error {
+	if !strings.HasPrefix(key, FlagPrefix) {
+		return nil
+	}
+
+	if _, violations := service.ParseFlag(value); len(violations) > 0 {
+		return &SchemaViolationError{
+			Key:        key,
+			Schema:     &Schema{Namespace: r.namespace, Prefix: FlagPrefix},
+			Violations: violations,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/clivern/zewi/internal/testsupport"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB initializes the global connection with a migrated sqlite
+// database and a keyring, both reset when the test ends
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	err := InitDB(Config{Driver: "sqlite", DataSource: testsupport.SQLite(t)})
+	require.NoError(t, err)
+	t.Cleanup(func() { CloseDB() })
+
+	SetKeyring(testsupport.Keyring(t))
+	t.Cleanup(func() { SetKeyring(nil) })
+
+	return GetDB()
+}
+
+// newPostgresTestDB initializes the global connection with the migrated
+// postgres database at ZEWI_TEST_POSTGRES_HOST, the test is skipped if it
+// is not set
+func newPostgresTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	host := os.Getenv("ZEWI_TEST_POSTGRES_HOST")
+	if host == "" {
+		t.Skip("ZEWI_TEST_POSTGRES_HOST is not set")
+	}
+
+	err := InitDB(Config{
+		Driver:   "postgres",
+		Host:     host,
+		Port:     5432,
+		Username: "postgres",
+		Password: "postgres",
+		Database: "zewi",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { CloseDB() })
+
+	testsupport.Migrate(t, GetDB(), GetDriver())
+
+	return GetDB()
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// ImportModeMerge creates and updates the imported options and keeps the others.
+	ImportModeMerge = "merge"
+	// ImportModeReplace makes the namespace hold exactly the imported options.
+	ImportModeReplace = "replace"
+)
+
+// ErrMaskedSecret is returned when an import holds the masked value of a
+// secret option that does not exist, so there is no value to keep.
+var ErrMaskedSecret = errors.New("masked secret value")
+
+// ImportReport lists the keys an import created, updated, deleted or left
+// unchanged, and the diff of the changed options.
+type ImportReport struct {
+	Created   []string
+	Updated   []string
+	Deleted   []string
+	Unchanged []string
+	// Changes holds the created, updated and deleted options sorted by key
+	Changes []ImportChange
+}
+
+// ImportChange is the diff of an option changed by an import. Action is one
+// of RevisionActionCreate, RevisionActionUpdate or RevisionActionDelete.
+// Before and After are nil if the option does not exist before or after the
+// import, and hold MaskedValue for secret options.
+type ImportChange struct {
+	Key    string
+	Action string
+	Before *string
+	After  *string
+}
+
+// Import writes the given option values into the namespace within a single
+// transaction. In replace mode options missing from values are deleted. Every
+// created or updated value is validated against the schema of its key first.
+// Options listed in secrets are written as secret, and options that are
+// already secret stay secret. Secret options whose value is masked, as
+// exported without revealing them, are left unchanged, and a masked value of
+// a missing option fails with ErrMaskedSecret. With dryRun set the report is
+// computed but nothing is written.
+func (r *OptionRepository) Import(values map[string]string, secrets []string, mode string, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{
+		Created:   []string{},
+		Updated:   []string{},
+		Deleted:   []string{},
+		Unchanged: []string{},
+		Changes:   []ImportChange{},
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	secret := make(map[string]bool, len(secrets))
+	for _, key := range secrets {
+		secret[key] = true
+	}
+
+	err := r.Transaction(func(tx *OptionRepository) error {
+		options, err := tx.List()
+		if err != nil {
+			return err
+		}
+
+		existing := make(map[string]*Option, len(options))
+		for _, option := range options {
+			existing[option.Key] = option
+
+			if _, ok := values[option.Key]; !ok && mode == ImportModeReplace {
+				report.Deleted = append(report.Deleted, option.Key)
+			}
+		}
+
+		for _, key := range keys {
+			option, ok := existing[key]
+			switch {
+			case !ok && values[key] == MaskedValue:
+				return fmt.Errorf("option %s: %w", key, ErrMaskedSecret)
+			case !ok:
+				report.Created = append(report.Created, key)
+			case option.Secret && values[key] == MaskedValue:
+				// Exported without revealing secrets
+				report.Unchanged = append(report.Unchanged, key)
+			case option.Value != values[key], secret[key] && !option.Secret:
+				report.Updated = append(report.Updated, key)
+			default:
+				report.Unchanged = append(report.Unchanged, key)
+			}
+		}
+
+		for _, key := range report.Created {
+			after := maskedValue(values[key], secret[key])
+			report.Changes = append(report.Changes, ImportChange{Key: key, Action: RevisionActionCreate, After: &after})
+		}
+		for _, key := range report.Updated {
+			option := existing[key]
+			before := option.DisplayValue(false)
+			after := maskedValue(values[key], option.Secret || secret[key])
+			report.Changes = append(report.Changes, ImportChange{Key: key, Action: RevisionActionUpdate, Before: &before, After: &after})
+		}
+		for _, key := range report.Deleted {
+			before := existing[key].DisplayValue(false)
+			report.Changes = append(report.Changes, ImportChange{Key: key, Action: RevisionActionDelete, Before: &before})
+		}
+		sort.Slice(report.Changes, func(i, j int) bool {
+			return report.Changes[i].Key < report.Changes[j].Key
+		})
+
+		for _, group := range [][]string{report.Created, report.Updated} {
+			for _, key := range group {
+				if err := tx.Schemas().Validate(key, values[key]); err != nil {
+					return err
+				}
+			}
+		}
+
+		if dryRun {
+			return nil
+		}
+
+		for _, key := range report.Deleted {
+			if err := tx.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		for _, key := range report.Created {
+			if _, _, err := tx.WithSecret(secret[key]).Upsert(key, values[key], nil); err != nil {
+				return err
+			}
+		}
+
+		// Updated options keep their expiry
+		for _, key := range report.Updated {
+			option := existing[key]
+			if _, _, err := tx.WithSecret(option.Secret || secret[key]).Upsert(key, values[key], option.ExpiresAt); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// maskedValue returns the value, or MaskedValue if it is secret.
+func maskedValue(value string, secret bool) string {
+	if secret {
+		return MaskedValue
+	}
+	return value
+}
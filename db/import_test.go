@@ -0,0 +1,105 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitImport(t *testing.T) {
+	t.Run("Rejects masked values of missing options", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+
+		_, err := repo.Import(map[string]string{"db.password": MaskedValue}, []string{"db.password"}, ImportModeMerge, false)
+		assert.True(t, errors.Is(err, ErrMaskedSecret))
+
+		option, err := repo.Get("db.password")
+		assert.NoError(t, err)
+		assert.Nil(t, option)
+	})
+
+	t.Run("Keeps masked secret values", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		_, _, err := repo.WithSecret(true).Upsert("db.password", `"s3cret"`, nil)
+		require.NoError(t, err)
+
+		report, err := repo.Import(map[string]string{"db.password": MaskedValue}, []string{"db.password"}, ImportModeMerge, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"db.password"}, report.Unchanged)
+
+		option, err := repo.Get("db.password")
+		assert.NoError(t, err)
+		assert.Equal(t, `"s3cret"`, option.Value)
+		assert.True(t, option.Secret)
+	})
+
+	t.Run("Writes secret options as secret", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		_, _, err := repo.Upsert("db.user", `"admin"`, nil)
+		require.NoError(t, err)
+
+		values := map[string]string{"db.password": `"s3cret"`, "db.user": `"admin"`}
+		report, err := repo.Import(values, []string{"db.password", "db.user"}, ImportModeMerge, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"db.password"}, report.Created)
+		assert.Equal(t, []string{"db.user"}, report.Updated)
+
+		for key, value := range values {
+			option, err := repo.Get(key)
+			assert.NoError(t, err)
+			assert.Equal(t, value, option.Value)
+			assert.True(t, option.Secret, key)
+		}
+	})
+
+	t.Run("Keeps secret options secret", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		_, _, err := repo.WithSecret(true).Upsert("db.password", `"s3cret"`, nil)
+		require.NoError(t, err)
+
+		_, err = repo.Import(map[string]string{"db.password": `"changed"`}, nil, ImportModeMerge, false)
+		assert.NoError(t, err)
+
+		option, err := repo.Get("db.password")
+		assert.NoError(t, err)
+		assert.Equal(t, `"changed"`, option.Value)
+		assert.True(t, option.Secret)
+	})
+
+	t.Run("Reports the diff with secrets masked", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		_, _, err := repo.Upsert("app.name", `"zewi"`, nil)
+		require.NoError(t, err)
+		_, _, err = repo.Upsert("app.old", `1`, nil)
+		require.NoError(t, err)
+		_, _, err = repo.WithSecret(true).Upsert("db.password", `"s3cret"`, nil)
+		require.NoError(t, err)
+
+		values := map[string]string{
+			"app.name":    `"zewi 2"`,
+			"app.new":     `true`,
+			"db.password": `"changed"`,
+		}
+		report, err := repo.Import(values, nil, ImportModeReplace, true)
+		assert.NoError(t, err)
+
+		str := func(s string) *string { return &s }
+		assert.Equal(t, []ImportChange{
+			{Key: "app.name", Action: RevisionActionUpdate, Before: str(`"zewi"`), After: str(`"zewi 2"`)},
+			{Key: "app.new", Action: RevisionActionCreate, After: str(`true`)},
+			{Key: "app.old", Action: RevisionActionDelete, Before: str(`1`)},
+			{Key: "db.password", Action: RevisionActionUpdate, Before: str(MaskedValue), After: str(MaskedValue)},
+		}, report.Changes)
+
+		// Nothing is written on a dry run
+		option, err := repo.Get("app.name")
+		assert.NoError(t, err)
+		assert.Equal(t, `"zewi"`, option.Value)
+	})
+}
@@ -0,0 +1,232 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+// ReservedLeasePrefix is the name prefix of the leases used by Zewi itself,
+// which can not be acquired through the lock API.
+const ReservedLeasePrefix = "zewi."
+
+// LeaderLease is the name of the lease held by the leader API replica.
+const LeaderLease = ReservedLeasePrefix + "leader"
+
+var isLeaderGauge = promauto.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "zewi_is_leader",
+		Help: "Whether this API replica is the leader running background work",
+	},
+)
+
+// Leadership describes the leader election state of an API replica.
+type Leadership struct {
+	Identity string
+	Leader   bool
+	// Since is when the replica became leader, zero if it is not
+	Since time.Time
+	// Holder is the leader lease as last seen, without its token, nil if
+	// the lease was never acquired
+	Holder *Lease
+}
+
+// Elector campaigns for the leader lease so background work runs on exactly
+// one API replica. The leader renews the lease every third of its TTL and
+// steps down as soon as it can not prove it still holds it.
+type Elector struct {
+	repo     *LeaseRepository
+	identity string
+	ttl      time.Duration
+
+	mu     sync.RWMutex
+	lease  *Lease
+	holder *Lease
+	since  time.Time
+}
+
+var (
+	// globalElector is the elector of the API replica, nil if not started
+	globalElector *Elector
+	// electorMu protects globalElector
+	electorMu sync.RWMutex
+)
+
+// SetElector sets the elector whose state is reported by GetElector.
+func SetElector(elector *Elector) {
+	electorMu.Lock()
+	defer electorMu.Unlock()
+
+	globalElector = elector
+}
+
+// GetElector returns the elector of the API replica, or nil if not started.
+func GetElector() *Elector {
+	electorMu.RLock()
+	defer electorMu.RUnlock()
+
+	return globalElector
+}
+
+// NewElector creates an elector campaigning under the given identity for a
+// leader lease lasting ttl.
+func NewElector(db *sql.DB, identity string, ttl time.Duration) *Elector {
+	return &Elector{
+		repo:     NewLeaseRepository(db),
+		identity: identity,
+		ttl:      ttl,
+	}
+}
+
+// Status returns the leader election state of the replica.
+func (e *Elector) Status() Leadership {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	status := Leadership{
+		Identity: e.identity,
+		Leader:   e.lease != nil,
+		Since:    e.since,
+	}
+
+	if e.holder != nil {
+		holder := *e.holder
+		holder.Token = ""
+		status.Holder = &holder
+	}
+	return status
+}
+
+// Run campaigns for leadership until the context is cancelled. While leader,
+// lead runs with a context cancelled as soon as leadership is lost. Before
+// returning, Run waits for lead and releases the lease so another replica
+// takes over without waiting for it to expire.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	interval := e.ttl / 3
+
+	log.Info().
+		Str("identity", e.identity).
+		Dur("ttl", e.ttl).
+		Msg("Starting leader election")
+
+	var led sync.WaitGroup
+	cancelLead := context.CancelFunc(func() {})
+
+	stepDown := func(reason string) {
+		cancelLead()
+		led.Wait()
+
+		e.mu.Lock()
+		e.lease = nil
+		e.since = time.Time{}
+		e.mu.Unlock()
+
+		isLeaderGauge.Set(0)
+		log.Warn().Str("identity", e.identity).Str("reason", reason).Msg("Stepped down as leader")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.mu.RLock()
+		lease := e.lease
+		e.mu.RUnlock()
+
+		if lease == nil {
+			acquired, ok, err := e.repo.Acquire(LeaderLease, e.identity, e.ttl)
+			switch {
+			case err != nil:
+				log.Error().Err(err).Msg("Failed to campaign for leadership")
+			case ok:
+				e.mu.Lock()
+				e.lease = acquired
+				e.holder = acquired
+				e.since = time.Now().UTC()
+				e.mu.Unlock()
+
+				isLeaderGauge.Set(1)
+				log.Info().
+					Str("identity", e.identity).
+					Int64("fence", acquired.Fence).
+					Msg("Elected as leader")
+
+				var leadCtx context.Context
+				leadCtx, cancelLead = context.WithCancel(ctx)
+				led.Add(1)
+				go func() {
+					defer led.Done()
+					lead(leadCtx)
+				}()
+			default:
+				e.mu.Lock()
+				e.holder = acquired
+				e.mu.Unlock()
+			}
+		} else {
+			renewed, ok, err := e.repo.Renew(LeaderLease, lease.Token, e.ttl)
+			switch {
+			case err != nil:
+				log.Error().Err(err).Msg("Failed to renew leader lease")
+
+				// Step down before the lease may expire, another replica
+				// could take over then
+				if time.Now().Add(interval).After(lease.ExpiresAt) {
+					stepDown("lease renewal failed")
+				}
+			case ok:
+				e.mu.Lock()
+				e.lease = renewed
+				e.holder = renewed
+				e.mu.Unlock()
+			default:
+				stepDown("lease lost")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			cancelLead()
+			e.resign(&led)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resign waits for the work of the leader to stop once the context of Run is
+// cancelled, then releases the lease.
+func (e *Elector) resign(led *sync.WaitGroup) {
+	led.Wait()
+
+	e.mu.Lock()
+	lease := e.lease
+	e.lease = nil
+	e.since = time.Time{}
+	e.mu.Unlock()
+
+	isLeaderGauge.Set(0)
+
+	if lease == nil {
+		return
+	}
+
+	released, err := e.repo.Release(LeaderLease, lease.Token)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to release leader lease")
+		return
+	}
+
+	if released {
+		log.Info().Str("identity", e.identity).Msg("Released leadership")
+	}
+}
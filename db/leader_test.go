@@ -0,0 +1,113 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitElector(t *testing.T) {
+	t.Run("Elects one leader and hands over on resign", func(t *testing.T) {
+		database := newTestDB(t)
+		ttl := 300 * time.Millisecond
+
+		first := NewElector(database, "replica-1", ttl)
+		second := NewElector(database, "replica-2", ttl)
+
+		firstCtx, resignFirst := context.WithCancel(context.Background())
+		secondCtx, resignSecond := context.WithCancel(context.Background())
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		leading := map[string]bool{}
+
+		run := func(ctx context.Context, elector *Elector) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				elector.Run(ctx, func(ctx context.Context) {
+					mu.Lock()
+					leading[elector.identity] = true
+					mu.Unlock()
+
+					<-ctx.Done()
+
+					mu.Lock()
+					leading[elector.identity] = false
+					mu.Unlock()
+				})
+			}()
+		}
+
+		run(firstCtx, first)
+		require.Eventually(t, func() bool { return first.Status().Leader }, time.Second, 10*time.Millisecond)
+
+		run(secondCtx, second)
+		require.Eventually(t, func() bool {
+			holder := second.Status().Holder
+			return holder != nil && holder.Owner == "replica-1"
+		}, time.Second, 10*time.Millisecond)
+		assert.False(t, second.Status().Leader)
+
+		// The lease is released, so the second replica takes over before it
+		// would have expired
+		resignFirst()
+		require.Eventually(t, func() bool { return second.Status().Leader }, ttl, 10*time.Millisecond)
+		assert.False(t, first.Status().Leader)
+
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return !leading["replica-1"] && leading["replica-2"]
+		}, time.Second, 10*time.Millisecond)
+
+		resignSecond()
+		wg.Wait()
+
+		lease, err := NewLeaseRepository(database).Get(LeaderLease)
+		assert.NoError(t, err)
+		assert.False(t, lease.Held())
+		assert.Greater(t, lease.Fence, int64(1))
+	})
+
+	t.Run("Steps down once the lease is lost", func(t *testing.T) {
+		database := newTestDB(t)
+		elector := NewElector(database, "replica-1", 300*time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		lost := make(chan struct{})
+		go func() {
+			defer close(done)
+			elector.Run(ctx, func(ctx context.Context) {
+				<-ctx.Done()
+				close(lost)
+			})
+		}()
+		require.Eventually(t, func() bool { return elector.Status().Leader }, time.Second, 10*time.Millisecond)
+
+		// Another process steals the lease, so renewing with the token fails
+		_, err := database.Exec("UPDATE leases SET token = 'stolen' WHERE name = ?", LeaderLease)
+		require.NoError(t, err)
+
+		select {
+		case <-lost:
+		case <-time.After(time.Second):
+			t.Fatal("leader work was not stopped")
+		}
+		assert.Eventually(t, func() bool { return !elector.Status().Leader }, time.Second, 10*time.Millisecond)
+	})
+}
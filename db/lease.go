@@ -0,0 +1,242 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+)
+
+// Lease grants its owner a named lock until it expires. Every acquisition gets
+// a new secret token proving ownership and a fencing token greater than the
+// one of any previous holder, so resources can reject writes of stale holders.
+type Lease struct {
+	Name  string
+	Owner string
+	// Token is the secret proving ownership, only known to the holder
+	Token string
+	// Fence is the fencing token of the acquisition
+	Fence      int64
+	AcquiredAt time.Time
+	ExpiresAt  time.Time
+}
+
+// Held reports whether the lease has not expired or been released.
+func (l *Lease) Held() bool {
+	return time.Now().Before(l.ExpiresAt)
+}
+
+// leaseColumns lists the columns scanned by scanLease.
+const leaseColumns = "name, owner, token, fence, acquired_at, expires_at"
+
+// scanLease scans a lease row selected with leaseColumns.
+func scanLease(row interface{ Scan(...interface{}) error }) (*Lease, error) {
+	lease := &Lease{}
+
+	err := row.Scan(
+		&lease.Name,
+		&lease.Owner,
+		&lease.Token,
+		&lease.Fence,
+		&lease.AcquiredAt,
+		&lease.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// newLeaseToken generates the secret token of an acquisition.
+func newLeaseToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// LeaseRepository handles database operations for leases.
+type LeaseRepository struct {
+	db     querier
+	driver string
+}
+
+// NewLeaseRepository creates a new lease repository.
+func NewLeaseRepository(db *sql.DB) *LeaseRepository {
+	return &LeaseRepository{
+		db:     db,
+		driver: GetDriver(),
+	}
+}
+
+// Acquire takes the lease with the given name for ttl unless it is held. It
+// reports whether the lease was acquired. If not, the current holder is
+// returned without its token.
+func (r *LeaseRepository) Acquire(name, owner string, ttl time.Duration) (*Lease, bool, error) {
+	token, err := newLeaseToken()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `INSERT INTO leases (name, owner, token, fence, acquired_at, expires_at)
+		VALUES ($1, $2, $3, 1, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			owner = EXCLUDED.owner,
+			token = EXCLUDED.token,
+			fence = leases.fence + 1,
+			acquired_at = EXCLUDED.acquired_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE leases.expires_at <= EXCLUDED.acquired_at
+		RETURNING ` + leaseColumns
+	} else {
+		query = `INSERT INTO leases (name, owner, token, fence, acquired_at, expires_at)
+		VALUES (?1, ?2, ?3, 1, ?4, ?5)
+		ON CONFLICT (name) DO UPDATE SET
+			owner = excluded.owner,
+			token = excluded.token,
+			fence = leases.fence + 1,
+			acquired_at = excluded.acquired_at,
+			expires_at = excluded.expires_at
+		WHERE leases.expires_at <= excluded.acquired_at
+		RETURNING ` + leaseColumns
+	}
+
+	// The holder may release the lease between both statements, so retry
+	for attempt := 0; attempt < 3; attempt++ {
+		now := time.Now().UTC()
+
+		lease, err := scanLease(r.db.QueryRow(query, name, owner, token, now, now.Add(ttl)))
+		if err == nil {
+			return lease, true, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, false, err
+		}
+
+		holder, err := r.Get(name)
+		if err != nil {
+			return nil, false, err
+		}
+		if holder != nil && holder.Held() {
+			return holder, false, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// Renew extends the lease held with the given token to expire after ttl. It
+// reports false if the token does not hold the lease anymore.
+func (r *LeaseRepository) Renew(name, token string, ttl time.Duration) (*Lease, bool, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `UPDATE leases SET expires_at = $1
+		WHERE name = $2 AND token = $3 AND expires_at > $4
+		RETURNING ` + leaseColumns
+	} else {
+		query = `UPDATE leases SET expires_at = ?
+		WHERE name = ? AND token = ? AND expires_at > ?
+		RETURNING ` + leaseColumns
+	}
+
+	now := time.Now().UTC()
+
+	lease, err := scanLease(r.db.QueryRow(query, now.Add(ttl), name, token, now))
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return lease, true, nil
+}
+
+// Release gives up the lease held with the given token. It reports false if
+// the token does not hold the lease anymore.
+func (r *LeaseRepository) Release(name, token string) (bool, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `UPDATE leases SET expires_at = $1, token = ''
+		WHERE name = $2 AND token = $3 AND expires_at > $1`
+	} else {
+		query = `UPDATE leases SET expires_at = ?1, token = ''
+		WHERE name = ?2 AND token = ?3 AND expires_at > ?1`
+	}
+
+	result, err := r.db.Exec(query, time.Now().UTC(), name, token)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Get retrieves a lease by name whether it is held or not, without its token.
+func (r *LeaseRepository) Get(name string) (*Lease, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = "SELECT " + leaseColumns + " FROM leases WHERE name = $1"
+	} else {
+		query = "SELECT " + leaseColumns + " FROM leases WHERE name = ?"
+	}
+
+	lease, err := scanLease(r.db.QueryRow(query, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lease.Token = ""
+	return lease, nil
+}
+
+// List retrieves the held leases ordered by name, without their tokens.
+func (r *LeaseRepository) List() ([]*Lease, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = "SELECT " + leaseColumns + " FROM leases WHERE expires_at > $1 ORDER BY name"
+	} else {
+		query = "SELECT " + leaseColumns + " FROM leases WHERE expires_at > ? ORDER BY name"
+	}
+
+	rows, err := r.db.Query(query, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []*Lease
+	for rows.Next() {
+		lease, err := scanLease(rows)
+		if err != nil {
+			return nil, err
+		}
+		lease.Token = ""
+		leases = append(leases, lease)
+	}
+
+	return leases, rows.Err()
+}
+
+// Validate reports whether a fencing token is the one of the current holder
+// of the lease. Resources guarded by the lease use it to reject stale holders.
+func (r *LeaseRepository) Validate(name string, fence int64) (bool, error) {
+	lease, err := r.Get(name)
+	if err != nil || lease == nil {
+		return false, err
+	}
+	return lease.Held() && lease.Fence == fence, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitLease(t *testing.T) {
+	t.Run("Refuses a held lease", func(t *testing.T) {
+		repo := NewLeaseRepository(newTestDB(t))
+
+		lease, ok, err := repo.Acquire("jobs.sync", "worker-1", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.NotEmpty(t, lease.Token)
+		assert.Equal(t, int64(1), lease.Fence)
+
+		holder, ok, err := repo.Acquire("jobs.sync", "worker-2", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, "worker-1", holder.Owner)
+		assert.Empty(t, holder.Token)
+	})
+
+	t.Run("Fences out the previous holder", func(t *testing.T) {
+		repo := NewLeaseRepository(newTestDB(t))
+
+		first, ok, err := repo.Acquire("jobs.sync", "worker-1", 50*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		time.Sleep(100 * time.Millisecond)
+
+		second, ok, err := repo.Acquire("jobs.sync", "worker-2", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Greater(t, second.Fence, first.Fence)
+		assert.NotEqual(t, first.Token, second.Token)
+
+		valid, err := repo.Validate("jobs.sync", first.Fence)
+		assert.NoError(t, err)
+		assert.False(t, valid)
+
+		valid, err = repo.Validate("jobs.sync", second.Fence)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+
+		// The token of the expired acquisition proves nothing anymore
+		_, ok, err = repo.Renew("jobs.sync", first.Token, time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = repo.Release("jobs.sync", first.Token)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		holder, err := repo.Get("jobs.sync")
+		assert.NoError(t, err)
+		assert.Equal(t, "worker-2", holder.Owner)
+	})
+
+	t.Run("Renews and releases with the token", func(t *testing.T) {
+		repo := NewLeaseRepository(newTestDB(t))
+
+		lease, ok, err := repo.Acquire("jobs.sync", "worker-1", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		renewed, ok, err := repo.Renew("jobs.sync", lease.Token, time.Hour)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, renewed.ExpiresAt.After(lease.ExpiresAt))
+		assert.Equal(t, lease.Fence, renewed.Fence)
+
+		ok, err = repo.Release("jobs.sync", lease.Token)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		leases, err := repo.List()
+		assert.NoError(t, err)
+		assert.Empty(t, leases)
+
+		// A released lease is acquired right away, with the next fence
+		next, ok, err := repo.Acquire("jobs.sync", "worker-2", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, lease.Fence+1, next.Fence)
+	})
+}
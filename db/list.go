@@ -0,0 +1,191 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// SortByKey orders options by key.
+	SortByKey = "key"
+	// SortByCreatedAt orders options by creation time, then key.
+	SortByCreatedAt = "created_at"
+	// SortByUpdatedAt orders options by last update time, then key.
+	SortByUpdatedAt = "updated_at"
+)
+
+// ListOptions filters, sorts and paginates an option listing.
+type ListOptions struct {
+	// Prefix only keeps options whose key starts with it.
+	Prefix string
+	// Query only keeps options whose key or value contains it, ignoring case.
+	// The values of secret options are not searched.
+	Query string
+	// Sort is one of SortByKey, SortByCreatedAt or SortByUpdatedAt.
+	Sort string
+	// Descending reverses the sort order.
+	Descending bool
+	// Limit is the maximum number of options returned, zero means no limit.
+	Limit int
+	// After continues the listing after the position of a previous page.
+	After *ListCursor
+}
+
+// ListCursor is the position of the last option of a page.
+type ListCursor struct {
+	Key string
+	// Time is the sort value of the option when not sorting by key.
+	Time time.Time
+}
+
+// OptionPage is a page of an option listing.
+type OptionPage struct {
+	Options []*Option
+	// Total is the number of options matching the filters across all pages.
+	Total int64
+	// Next is the position to continue from, nil on the last page.
+	Next *ListCursor
+}
+
+// IsValidSort reports whether options can be sorted by the given field.
+func IsValidSort(sort string) bool {
+	return sort == SortByKey || sort == SortByCreatedAt || sort == SortByUpdatedAt
+}
+
+// escapeLike escapes the LIKE wildcards of a search term.
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
+
+// Find retrieves a page of the live options in the namespace matching the
+// given filters, using keyset pagination so deep pages stay cheap.
+func (r *OptionRepository) Find(opts ListOptions) (*OptionPage, error) {
+	postgres := r.driver == "postgres" || r.driver == "postgresql"
+
+	sort := opts.Sort
+	if sort == "" {
+		sort = SortByKey
+	}
+	if !IsValidSort(sort) {
+		return nil, fmt.Errorf("unsupported sort field: %s", sort)
+	}
+
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		if postgres {
+			return fmt.Sprintf("$%d", len(args))
+		}
+		return "?"
+	}
+
+	conditions := []string{
+		"namespace = " + arg(r.namespace),
+		"deleted_at IS NULL",
+		"(expires_at IS NULL OR expires_at > " + arg(time.Now().UTC()) + ")",
+	}
+
+	if opts.Prefix != "" {
+		conditions = append(conditions, fmt.Sprintf("substr(key, 1, %s) = %s", arg(len(opts.Prefix)), arg(opts.Prefix)))
+	}
+
+	if opts.Query != "" {
+		like := "LIKE"
+		if postgres {
+			like = "ILIKE"
+		}
+		pattern := "%" + escapeLike(opts.Query) + "%"
+		conditions = append(conditions, fmt.Sprintf(
+			`(key %s %s ESCAPE '\' OR (NOT secret AND value %s %s ESCAPE '\'))`,
+			like, arg(pattern), like, arg(pattern),
+		))
+	}
+
+	page := &OptionPage{}
+
+	where := strings.Join(conditions, " AND ")
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM options WHERE "+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if opts.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	// sqlite stores times as text, rows defaulting to CURRENT_TIMESTAMP and
+	// rows written by the driver in different formats. Both are ordered and
+	// compared in one format, so the cursor matches the row it was taken from.
+	normalize := func(expr string) string { return expr }
+	if !postgres {
+		normalize = func(expr string) string {
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%f', %s)", expr)
+		}
+	}
+	column := normalize(sort)
+
+	if opts.After != nil {
+		if sort == SortByKey {
+			conditions = append(conditions, fmt.Sprintf("key %s %s", comparison, arg(opts.After.Key)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf(
+				"(%s %s %s OR (%s = %s AND key %s %s))",
+				column, comparison, normalize(arg(opts.After.Time.UTC())),
+				column, normalize(arg(opts.After.Time.UTC())),
+				comparison, arg(opts.After.Key),
+			))
+		}
+	}
+
+	query := "SELECT " + optionColumns + " FROM options WHERE " + strings.Join(conditions, " AND ")
+	if sort == SortByKey {
+		query += fmt.Sprintf(" ORDER BY key %s", direction)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, key %s", column, direction, direction)
+	}
+
+	// Fetch one extra row to know whether another page follows
+	if opts.Limit > 0 {
+		query += " LIMIT " + arg(opts.Limit+1)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		option, err := scanOption(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.openOption(option); err != nil {
+			return nil, err
+		}
+		page.Options = append(page.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.Limit > 0 && len(page.Options) > opts.Limit {
+		page.Options = page.Options[:opts.Limit]
+
+		last := page.Options[len(page.Options)-1]
+		page.Next = &ListCursor{Key: last.Key}
+		switch sort {
+		case SortByCreatedAt:
+			page.Next.Time = last.CreatedAt
+		case SortByUpdatedAt:
+			page.Next.Time = last.UpdatedAt
+		}
+	}
+
+	return page, nil
+}
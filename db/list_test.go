@@ -0,0 +1,73 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitFind(t *testing.T) {
+	t.Run("Pages through rows with legacy timestamps", func(t *testing.T) {
+		database := newTestDB(t)
+		repo := NewOptionRepository(database)
+
+		keys := []string{"app.a", "app.b", "app.c", "app.d", "app.e"}
+		for _, key := range keys {
+			_, _, err := repo.Upsert(key, `1`, nil)
+			require.NoError(t, err)
+		}
+
+		// Rows created before the driver wrote the times, in the format of
+		// CURRENT_TIMESTAMP, next to rows written by the driver
+		_, err := database.Exec("UPDATE options SET created_at = '2025-01-01 12:00:00' WHERE key IN ('app.a', 'app.b', 'app.c')")
+		require.NoError(t, err)
+
+		for _, descending := range []bool{false, true} {
+			var seen []string
+			opts := ListOptions{Sort: SortByCreatedAt, Descending: descending, Limit: 1}
+
+			for {
+				page, err := repo.Find(opts)
+				require.NoError(t, err)
+				assert.Equal(t, int64(len(keys)), page.Total)
+
+				for _, option := range page.Options {
+					seen = append(seen, option.Key)
+				}
+				if page.Next == nil {
+					break
+				}
+				require.Less(t, len(seen), len(keys))
+				opts.After = page.Next
+			}
+
+			if descending {
+				assert.Equal(t, []string{"app.e", "app.d", "app.c", "app.b", "app.a"}, seen)
+			} else {
+				assert.Equal(t, keys, seen)
+			}
+		}
+	})
+
+	t.Run("Does not search secret values", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		_, _, err := repo.Upsert("app.name", `"needle"`, nil)
+		require.NoError(t, err)
+		_, _, err = repo.WithSecret(true).Upsert("app.password", `"needle"`, nil)
+		require.NoError(t, err)
+		_, _, err = repo.WithSecret(true).Upsert("needle.token", `"s3cret"`, nil)
+		require.NoError(t, err)
+
+		page, err := repo.Find(ListOptions{Query: "NEEDLE"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), page.Total)
+		require.Len(t, page.Options, 2)
+		assert.Equal(t, "app.name", page.Options[0].Key)
+		assert.Equal(t, "needle.token", page.Options[1].Key)
+	})
+}
@@ -6,105 +6,665 @@ package db
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/clivern/zewi/service"
 )
 
+// DefaultNamespace is the namespace used when none is given.
+const DefaultNamespace = "default"
+
+// maxPatchAttempts bounds the retries of Patch under concurrent writes.
+const maxPatchAttempts = 5
+
+// ErrOptionNotFound is returned when an option does not exist.
+var ErrOptionNotFound = errors.New("option not found")
+
+// ErrVersionMismatch is returned when an option was modified concurrently.
+var ErrVersionMismatch = errors.New("option version mismatch")
+
 // Option represents a key-value option in the database.
 type Option struct {
 	ID        int64
+	Namespace string
 	Key       string
 	Value     string
+	Version   int64
+	ExpiresAt *time.Time
+	// DeletedAt is set while the option is in the trash
+	DeletedAt *time.Time
+	// Secret options are stored encrypted with a data key wrapped by KeyID
+	Secret    bool
+	KeyID     string
+	dataKey   string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// sealed returns the value of the option as stored in the database.
+func (o *Option) sealed() *sealedValue {
+	return &sealedValue{
+		Value:   o.Value,
+		Secret:  o.Secret,
+		KeyID:   o.KeyID,
+		DataKey: o.dataKey,
+	}
+}
+
+// Namespace represents a namespace and the number of live options in it.
+type Namespace struct {
+	Name    string
+	Options int64
+}
+
+// optionColumns lists the columns scanned by scanOption.
+const optionColumns = "id, namespace, key, value, version, expires_at, deleted_at, secret, key_id, data_key, created_at, updated_at"
+
+// scanOption scans an option row selected with optionColumns. The value of a
+// secret option is left encrypted until openOption.
+func scanOption(row interface{ Scan(...interface{}) error }) (*Option, error) {
+	option := &Option{}
+	var expiresAt, deletedAt sql.NullTime
+	var keyID, dataKey sql.NullString
+
+	err := row.Scan(
+		&option.ID,
+		&option.Namespace,
+		&option.Key,
+		&option.Value,
+		&option.Version,
+		&expiresAt,
+		&deletedAt,
+		&option.Secret,
+		&keyID,
+		&dataKey,
+		&option.CreatedAt,
+		&option.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		t := expiresAt.Time.UTC()
+		option.ExpiresAt = &t
+	}
+	if deletedAt.Valid {
+		t := deletedAt.Time.UTC()
+		option.DeletedAt = &t
+	}
+	option.KeyID = keyID.String
+	option.dataKey = dataKey.String
+	return option, nil
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // OptionRepository handles database operations for options.
 type OptionRepository struct {
-	db     *sql.DB
-	driver string
+	db        querier
+	conn      *sql.DB
+	driver    string
+	namespace string
+	// changes collects the changes of the current transaction until commit
+	changes *[]Change
+	// keyring encrypts the values of secret options, nil if not configured
+	keyring *service.Keyring
+	// secret, if set, marks written values as secret or not
+	secret *bool
+	// cache caches options read by key, nil if disabled or within a transaction
+	cache *optionCache
+	// trail collects the committed changes for the audit log, nil if not audited
+	trail *AuditTrail
 }
 
-// NewOptionRepository creates a new option repository.
+// NewOptionRepository creates a new option repository scoped to the default namespace.
 func NewOptionRepository(db *sql.DB) *OptionRepository {
 	return &OptionRepository{
-		db:     db,
-		driver: GetDriver(),
+		db:        db,
+		conn:      db,
+		driver:    GetDriver(),
+		namespace: DefaultNamespace,
+		keyring:   GetKeyring(),
+		cache:     getCache(),
 	}
 }
 
+// WithNamespace returns a copy of the repository scoped to the given namespace.
+func (r *OptionRepository) WithNamespace(namespace string) *OptionRepository {
+	return &OptionRepository{
+		db:        r.db,
+		conn:      r.conn,
+		driver:    r.driver,
+		namespace: namespace,
+		changes:   r.changes,
+		keyring:   r.keyring,
+		secret:    r.secret,
+		cache:     r.cache,
+		trail:     r.trail,
+	}
+}
+
+// WithSecret returns a copy of the repository that marks the options it
+// writes as secret or not. Values of secret options are encrypted at rest.
+// Without it, written options keep being secret or not.
+func (r *OptionRepository) WithSecret(secret bool) *OptionRepository {
+	repo := r.WithNamespace(r.namespace)
+	repo.secret = &secret
+	return repo
+}
+
+// Namespace returns the namespace the repository is scoped to.
+func (r *OptionRepository) Namespace() string {
+	return r.namespace
+}
+
+// Schemas returns a schema repository scoped to the same namespace and, if
+// any, the same transaction.
+func (r *OptionRepository) Schemas() *SchemaRepository {
+	return &SchemaRepository{
+		db:        r.db,
+		driver:    r.driver,
+		namespace: r.namespace,
+	}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise.
+// Calling Transaction on a repository that is already bound to a transaction
+// runs fn within the existing one. Changes made by fn are applied to the
+// option cache and published to the change feed once the transaction is
+// committed.
+func (r *OptionRepository) Transaction(fn func(*OptionRepository) error) error {
+	if r.conn == nil {
+		return fn(r)
+	}
+
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	txRepo := &OptionRepository{
+		db:        tx,
+		driver:    r.driver,
+		namespace: r.namespace,
+		changes:   &[]Change{},
+		keyring:   r.keyring,
+		secret:    r.secret,
+		trail:     r.trail,
+	}
+
+	if err := fn(txRepo); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, change := range *txRepo.changes {
+		r.commitChange(change)
+	}
+
+	return nil
+}
+
 // Create inserts a new option into the database.
 func (r *OptionRepository) Create(key, value string) error {
 	var query string
 	if r.driver == "postgres" || r.driver == "postgresql" {
-		query = "INSERT INTO options (key, value) VALUES ($1, $2)"
+		query = `INSERT INTO options (namespace, key, value, version, secret, key_id, data_key, created_at, updated_at)
+		VALUES ($1, $2, $3, 1, $4, $5, $6, $7, $8)`
 	} else {
-		query = "INSERT INTO options (key, value) VALUES (?, ?)"
+		query = `INSERT INTO options (namespace, key, value, version, secret, key_id, data_key, created_at, updated_at)
+		VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?)`
 	}
-	_, err := r.db.Exec(query, key, value)
-	return err
+
+	return r.Transaction(func(tx *OptionRepository) error {
+		sealed, err := tx.sealValue(key, value)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		_, err = tx.db.Exec(
+			query,
+			r.namespace,
+			key,
+			sealed.Value,
+			sealed.Secret,
+			nullString(sealed.KeyID),
+			nullString(sealed.DataKey),
+			now,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+		return tx.recordRevision(key, sealed, 1, RevisionActionCreate)
+	})
 }
 
-// Get retrieves an option by key.
+// Upsert atomically creates an option or updates its value if the key
+// already exists, and returns the stored row and whether the option was
+// created. An option that expired but was not swept yet, or that is in the
+// trash, is created again. Its version keeps increasing, so versions of the
+// previous option never match the new one. A nil expiresAt means the option
+// never expires.
+func (r *OptionRepository) Upsert(key, value string, expiresAt *time.Time) (*Option, bool, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `INSERT INTO options (namespace, key, value, version, secret, key_id, data_key, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, 1, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (namespace, key) DO UPDATE SET
+			value = EXCLUDED.value,
+			secret = EXCLUDED.secret,
+			key_id = EXCLUDED.key_id,
+			data_key = EXCLUDED.data_key,
+			version = options.version + 1,
+			created_at = CASE WHEN options.deleted_at IS NOT NULL OR options.expires_at <= EXCLUDED.updated_at THEN EXCLUDED.created_at ELSE options.created_at END,
+			expires_at = EXCLUDED.expires_at,
+			deleted_at = NULL,
+			updated_at = EXCLUDED.updated_at
+		RETURNING ` + optionColumns
+	} else {
+		query = `INSERT INTO options (namespace, key, value, version, secret, key_id, data_key, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (namespace, key) DO UPDATE SET
+			value = excluded.value,
+			secret = excluded.secret,
+			key_id = excluded.key_id,
+			data_key = excluded.data_key,
+			version = options.version + 1,
+			created_at = CASE WHEN options.deleted_at IS NOT NULL OR options.expires_at <= excluded.updated_at THEN excluded.created_at ELSE options.created_at END,
+			expires_at = excluded.expires_at,
+			deleted_at = NULL,
+			updated_at = excluded.updated_at
+		RETURNING ` + optionColumns
+	}
+
+	var option *Option
+	created := false
+	err := r.Transaction(func(tx *OptionRepository) error {
+		sealed, err := tx.sealValue(key, value)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		option, err = scanOption(tx.db.QueryRow(
+			query,
+			r.namespace,
+			key,
+			sealed.Value,
+			sealed.Secret,
+			nullString(sealed.KeyID),
+			nullString(sealed.DataKey),
+			nullTime(expiresAt),
+			now,
+			now,
+		))
+		if err != nil {
+			return err
+		}
+		option.Value = value
+
+		// created_at is only set to the time of the write when the option
+		// was missing, expired or in the trash, a live option keeps its own
+		action := RevisionActionUpdate
+		if option.CreatedAt.Equal(option.UpdatedAt) {
+			created = true
+			action = RevisionActionCreate
+		}
+		return tx.recordRevision(key, sealed, option.Version, action)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return option, created, nil
+}
+
+// Get retrieves an option by key. Expired and deleted options are treated as
+// missing. Outside of transactions, options that are not secret are read
+// through the option cache.
 func (r *OptionRepository) Get(key string) (*Option, error) {
-	option := &Option{}
+	if r.cache == nil {
+		return r.get(key)
+	}
+
+	if option, ok := r.cache.get(r.namespace, key); ok {
+		return option, nil
+	}
+
+	generation := r.cache.generation.Load()
+
+	option, err := r.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if option == nil || !option.Secret {
+		r.cache.set(r.namespace, key, option, generation)
+	}
+	return option, nil
+}
+
+// get reads an option by key from the database.
+func (r *OptionRepository) get(key string) (*Option, error) {
 	var query string
 	if r.driver == "postgres" || r.driver == "postgresql" {
-		query = `SELECT id, key, value, created_at, updated_at
+		query = `SELECT ` + optionColumns + `
 		FROM options
-		WHERE key = $1`
+		WHERE namespace = $1 AND key = $2 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $3)`
 	} else {
-		query = `SELECT id, key, value, created_at, updated_at
+		query = `SELECT ` + optionColumns + `
 		FROM options
-		WHERE key = ?`
+		WHERE namespace = ? AND key = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`
 	}
-	err := r.db.QueryRow(query, key).Scan(
-		&option.ID,
-		&option.Key,
-		&option.Value,
-		&option.CreatedAt,
-		&option.UpdatedAt,
-	)
 
+	option, err := scanOption(r.db.QueryRow(query, r.namespace, key, time.Now().UTC()))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
+	if err := r.openOption(option); err != nil {
+		return nil, err
+	}
 	return option, nil
 }
 
-// Update updates an option value.
+// Update updates an option value and bumps its version.
 func (r *OptionRepository) Update(key, value string) error {
 	var query string
 	if r.driver == "postgres" || r.driver == "postgresql" {
 		query = `UPDATE options SET
-			value = $1, updated_at = $2
-		WHERE key = $3`
+			value = $1, secret = $2, key_id = $3, data_key = $4, version = version + 1, updated_at = $5
+		WHERE namespace = $6 AND key = $7 AND deleted_at IS NULL
+		RETURNING version`
 	} else {
 		query = `UPDATE options SET
-			value = ?, updated_at = ?
-		WHERE key = ?`
+			value = ?, secret = ?, key_id = ?, data_key = ?, version = version + 1, updated_at = ?
+		WHERE namespace = ? AND key = ? AND deleted_at IS NULL
+		RETURNING version`
 	}
-	_, err := r.db.Exec(query, value, time.Now().UTC(), key)
-	return err
+
+	return r.Transaction(func(tx *OptionRepository) error {
+		sealed, err := tx.sealValue(key, value)
+		if err != nil {
+			return err
+		}
+
+		var version int64
+		err = tx.db.QueryRow(
+			query,
+			sealed.Value,
+			sealed.Secret,
+			nullString(sealed.KeyID),
+			nullString(sealed.DataKey),
+			time.Now().UTC(),
+			r.namespace,
+			key,
+		).Scan(&version)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.recordRevision(key, sealed, version, RevisionActionUpdate)
+	})
+}
+
+// CompareAndSwap updates an option value and expiry only if its current
+// version matches the expected one, or if it exists when the expected version
+// is zero. It returns the stored row, or nil if the swap did not happen.
+func (r *OptionRepository) CompareAndSwap(key, value string, expiresAt *time.Time, expectedVersion int64) (*Option, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `UPDATE options SET
+			value = $1, version = version + 1, expires_at = $2, updated_at = $3, secret = $7, key_id = $8, data_key = $9
+		WHERE namespace = $4 AND key = $5 AND ($6 = 0 OR version = $6) AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $3)
+		RETURNING ` + optionColumns
+	} else {
+		query = `UPDATE options SET
+			value = ?1, version = version + 1, expires_at = ?2, updated_at = ?3, secret = ?7, key_id = ?8, data_key = ?9
+		WHERE namespace = ?4 AND key = ?5 AND (?6 = 0 OR version = ?6) AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?3)
+		RETURNING ` + optionColumns
+	}
+
+	var option *Option
+	err := r.Transaction(func(tx *OptionRepository) error {
+		sealed, err := tx.sealValue(key, value)
+		if err != nil {
+			return err
+		}
+
+		stored, err := scanOption(tx.db.QueryRow(
+			query,
+			sealed.Value,
+			nullTime(expiresAt),
+			time.Now().UTC(),
+			r.namespace,
+			key,
+			expectedVersion,
+			sealed.Secret,
+			nullString(sealed.KeyID),
+			nullString(sealed.DataKey),
+		))
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		option = stored
+		option.Value = value
+		return tx.recordRevision(key, sealed, option.Version, RevisionActionUpdate)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return option, nil
 }
 
-// Delete removes an option from the database.
+// Patch atomically transforms the value of an option with apply. When
+// expectedVersion is zero the transformation is retried if the option changes
+// concurrently, otherwise ErrVersionMismatch is returned as soon as the stored
+// version differs.
+func (r *OptionRepository) Patch(key string, expectedVersion int64, apply func(value string) (string, error)) (*Option, error) {
+	for attempt := 0; attempt < maxPatchAttempts; attempt++ {
+		var option *Option
+
+		err := r.Transaction(func(tx *OptionRepository) error {
+			current, err := tx.Get(key)
+			if err != nil {
+				return err
+			}
+			if current == nil {
+				return ErrOptionNotFound
+			}
+			if expectedVersion > 0 && current.Version != expectedVersion {
+				return ErrVersionMismatch
+			}
+
+			value, err := apply(current.Value)
+			if err != nil {
+				return err
+			}
+
+			option, err = tx.CompareAndSwap(key, value, current.ExpiresAt, current.Version)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if option != nil {
+			return option, nil
+		}
+		if expectedVersion > 0 {
+			break
+		}
+	}
+
+	return nil, ErrVersionMismatch
+}
+
+// Delete moves an option to the trash, from where it can be restored until
+// the trash is purged.
 func (r *OptionRepository) Delete(key string) error {
 	var query string
 	if r.driver == "postgres" || r.driver == "postgresql" {
-		query = "DELETE FROM options WHERE key = $1"
+		query = `UPDATE options SET deleted_at = $1
+		WHERE namespace = $2 AND key = $3 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $1)
+		RETURNING namespace, key, version`
 	} else {
-		query = "DELETE FROM options WHERE key = ?"
+		query = `UPDATE options SET deleted_at = ?1
+		WHERE namespace = ?2 AND key = ?3 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?1)
+		RETURNING namespace, key, version`
 	}
-	_, err := r.db.Exec(query, key)
+
+	_, err := r.deleteWhere(query, time.Now().UTC(), r.namespace, key)
 	return err
 }
 
-// List retrieves all options from the database.
+// Destroy permanently removes an option, whether it is live or in the trash.
+// It reports whether an option was removed.
+func (r *OptionRepository) Destroy(key string) (bool, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = "DELETE FROM options WHERE namespace = $1 AND key = $2"
+	} else {
+		query = "DELETE FROM options WHERE namespace = ? AND key = ?"
+	}
+
+	destroyed := false
+	err := r.Transaction(func(tx *OptionRepository) error {
+		// Record a delete revision unless the option was already deleted
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+
+		result, err := tx.db.Exec(query, r.namespace, key)
+		if err != nil {
+			return err
+		}
+
+		count, err := result.RowsAffected()
+		destroyed = count > 0
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return destroyed, nil
+}
+
+// Restore moves an option out of the trash and bumps its version. It returns
+// nil if the option is not in the trash or expired meanwhile.
+func (r *OptionRepository) Restore(key string) (*Option, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `UPDATE options SET
+			deleted_at = NULL, version = version + 1, updated_at = $1
+		WHERE namespace = $2 AND key = $3 AND deleted_at IS NOT NULL AND (expires_at IS NULL OR expires_at > $1)
+		RETURNING ` + optionColumns
+	} else {
+		query = `UPDATE options SET
+			deleted_at = NULL, version = version + 1, updated_at = ?1
+		WHERE namespace = ?2 AND key = ?3 AND deleted_at IS NOT NULL AND (expires_at IS NULL OR expires_at > ?1)
+		RETURNING ` + optionColumns
+	}
+
+	var option *Option
+	err := r.Transaction(func(tx *OptionRepository) error {
+		var err error
+
+		option, err = scanOption(tx.db.QueryRow(query, time.Now().UTC(), r.namespace, key))
+		if err == sql.ErrNoRows {
+			option = nil
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.recordRevision(key, option.sealed(), option.Version, RevisionActionRestore); err != nil {
+			return err
+		}
+		return tx.openOption(option)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return option, nil
+}
+
+// Trash retrieves the deleted options of the namespace that can still be
+// restored, most recently deleted first.
+func (r *OptionRepository) Trash() ([]*Option, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT ` + optionColumns + `
+		FROM options
+		WHERE namespace = $1 AND deleted_at IS NOT NULL AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY deleted_at DESC, key`
+	} else {
+		query = `SELECT ` + optionColumns + `
+		FROM options
+		WHERE namespace = ? AND deleted_at IS NOT NULL AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY deleted_at DESC, key`
+	}
+
+	rows, err := r.db.Query(query, r.namespace, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var options []*Option
+	for rows.Next() {
+		option, err := scanOption(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.openOption(option); err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+
+	return options, rows.Err()
+}
+
+// List retrieves all options of the namespace that have not expired or been
+// deleted.
 func (r *OptionRepository) List() ([]*Option, error) {
-	rows, err := r.db.Query("SELECT id, key, value, created_at, updated_at FROM options ORDER BY key")
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT ` + optionColumns + `
+		FROM options
+		WHERE namespace = $1 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY key`
+	} else {
+		query = `SELECT ` + optionColumns + `
+		FROM options
+		WHERE namespace = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY key`
+	}
+
+	rows, err := r.db.Query(query, r.namespace, time.Now().UTC())
 	if err != nil {
 		return nil, err
 	}
@@ -112,14 +672,11 @@ func (r *OptionRepository) List() ([]*Option, error) {
 
 	var options []*Option
 	for rows.Next() {
-		option := &Option{}
-		if err := rows.Scan(
-			&option.ID,
-			&option.Key,
-			&option.Value,
-			&option.CreatedAt,
-			&option.UpdatedAt,
-		); err != nil {
+		option, err := scanOption(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.openOption(option); err != nil {
 			return nil, err
 		}
 		options = append(options, option)
@@ -127,3 +684,141 @@ func (r *OptionRepository) List() ([]*Option, error) {
 
 	return options, rows.Err()
 }
+
+// ListNamespaces retrieves every namespace that holds at least one live option.
+func (r *OptionRepository) ListNamespaces() ([]*Namespace, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT namespace, COUNT(*)
+		FROM options
+		WHERE deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $1)
+		GROUP BY namespace
+		ORDER BY namespace`
+	} else {
+		query = `SELECT namespace, COUNT(*)
+		FROM options
+		WHERE deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+		GROUP BY namespace
+		ORDER BY namespace`
+	}
+
+	rows, err := r.db.Query(query, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var namespaces []*Namespace
+	for rows.Next() {
+		namespace := &Namespace{}
+		if err := rows.Scan(&namespace.Name, &namespace.Options); err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, namespace)
+	}
+
+	return namespaces, rows.Err()
+}
+
+// DeleteNamespace moves every live option of the namespace to the trash and
+// returns how many options were deleted.
+func (r *OptionRepository) DeleteNamespace() (int, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `UPDATE options SET deleted_at = $1
+		WHERE namespace = $2 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $1)
+		RETURNING namespace, key, version`
+	} else {
+		query = `UPDATE options SET deleted_at = ?1
+		WHERE namespace = ?2 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?1)
+		RETURNING namespace, key, version`
+	}
+
+	return r.deleteWhere(query, time.Now().UTC(), r.namespace)
+}
+
+// PurgeExpired deletes every expired option across all namespaces and records
+// a delete revision for each of them. It returns the number of purged options.
+// Expired options in the trash are left to PurgeDeleted.
+func (r *OptionRepository) PurgeExpired() (int, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = "DELETE FROM options WHERE expires_at <= $1 AND deleted_at IS NULL RETURNING namespace, key, version"
+	} else {
+		query = "DELETE FROM options WHERE expires_at <= ? AND deleted_at IS NULL RETURNING namespace, key, version"
+	}
+
+	return r.deleteWhere(query, time.Now().UTC())
+}
+
+// PurgeDeleted permanently removes the options across all namespaces that
+// were moved to the trash before the given time, and returns how many were
+// removed. Their delete revisions were recorded when they were deleted.
+func (r *OptionRepository) PurgeDeleted(before time.Time) (int, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = "DELETE FROM options WHERE deleted_at <= $1"
+	} else {
+		query = "DELETE FROM options WHERE deleted_at <= ?"
+	}
+
+	result, err := r.db.Exec(query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// deleteWhere runs a DELETE or UPDATE ... RETURNING namespace, key, version
+// statement and records a delete revision for every removed option.
+func (r *OptionRepository) deleteWhere(query string, args ...interface{}) (int, error) {
+	deleted := 0
+
+	err := r.Transaction(func(tx *OptionRepository) error {
+		rows, err := tx.db.Query(query, args...)
+		if err != nil {
+			return err
+		}
+
+		var items []*Option
+		for rows.Next() {
+			item := &Option{}
+			if err := rows.Scan(&item.Namespace, &item.Key, &item.Version); err != nil {
+				rows.Close()
+				return err
+			}
+			items = append(items, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			err := tx.WithNamespace(item.Namespace).recordRevision(item.Key, nil, item.Version, RevisionActionDelete)
+			if err != nil {
+				return err
+			}
+		}
+
+		deleted = len(items)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// nullTime converts an optional time into a value suitable for a nullable column.
+func nullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitCompareAndSwap(t *testing.T) {
+	t.Run("Returns the stored option", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		current, _, err := repo.Upsert("app.name", `"zewi"`, nil)
+		require.NoError(t, err)
+
+		option, err := repo.CompareAndSwap("app.name", `"zewi 2"`, nil, current.Version)
+		assert.NoError(t, err)
+		require.NotNil(t, option)
+		assert.Equal(t, `"zewi 2"`, option.Value)
+		assert.Equal(t, current.Version+1, option.Version)
+
+		stored, err := repo.Get("app.name")
+		assert.NoError(t, err)
+		assert.Equal(t, option.Version, stored.Version)
+	})
+
+	t.Run("Refuses a stale version", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		current, _, err := repo.Upsert("app.name", `"zewi"`, nil)
+		require.NoError(t, err)
+		_, _, err = repo.Upsert("app.name", `"zewi 2"`, nil)
+		require.NoError(t, err)
+
+		option, err := repo.CompareAndSwap("app.name", `"zewi 3"`, nil, current.Version)
+		assert.NoError(t, err)
+		assert.Nil(t, option)
+
+		stored, err := repo.Get("app.name")
+		assert.NoError(t, err)
+		assert.Equal(t, `"zewi 2"`, stored.Value)
+	})
+
+	t.Run("Swaps any version of an existing option", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+
+		option, err := repo.CompareAndSwap("app.name", `"zewi"`, nil, 0)
+		assert.NoError(t, err)
+		assert.Nil(t, option)
+
+		_, _, err = repo.Upsert("app.name", `"zewi"`, nil)
+		require.NoError(t, err)
+
+		option, err = repo.CompareAndSwap("app.name", `"zewi 2"`, nil, 0)
+		assert.NoError(t, err)
+		require.NotNil(t, option)
+		assert.Equal(t, int64(2), option.Version)
+	})
+}
+
+func TestUnitUpsert(t *testing.T) {
+	t.Run("Reports whether the option was created", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+
+		option, created, err := repo.Upsert("app.name", `"zewi"`, nil)
+		assert.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, int64(1), option.Version)
+
+		option, created, err = repo.Upsert("app.name", `"zewi 2"`, nil)
+		assert.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, int64(2), option.Version)
+	})
+
+	t.Run("Keeps counting versions of recreated options", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		_, _, err := repo.Upsert("app.name", `"zewi"`, nil)
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete("app.name"))
+
+		option, created, err := repo.Upsert("app.name", `"zewi 2"`, nil)
+		assert.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, int64(2), option.Version)
+
+		// The version of the trashed option does not match the new one
+		swapped, err := repo.CompareAndSwap("app.name", `"zewi 3"`, nil, 1)
+		assert.NoError(t, err)
+		assert.Nil(t, swapped)
+
+		revisions, err := repo.History("app.name", 10)
+		assert.NoError(t, err)
+		require.NotEmpty(t, revisions)
+		assert.Equal(t, RevisionActionCreate, revisions[0].Action)
+	})
+
+	t.Run("Keeps counting versions of expired options", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+		expiresAt := time.Now().UTC().Add(-time.Second)
+		_, _, err := repo.Upsert("app.name", `"zewi"`, &expiresAt)
+		require.NoError(t, err)
+
+		option, created, err := repo.Upsert("app.name", `"zewi 2"`, nil)
+		assert.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, int64(2), option.Version)
+	})
+}
@@ -0,0 +1,338 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	// RevisionActionCreate marks a revision that created an option
+	RevisionActionCreate = "create"
+	// RevisionActionUpdate marks a revision that changed an option value
+	RevisionActionUpdate = "update"
+	// RevisionActionDelete marks a revision that removed an option
+	RevisionActionDelete = "delete"
+	// RevisionActionRestore marks a revision that restored an option from the trash
+	RevisionActionRestore = "restore"
+)
+
+// ErrRevisionNotFound is returned when a revision does not exist for a key.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// ErrRevisionDeleted is returned when reverting to a delete revision.
+var ErrRevisionDeleted = errors.New("revision is a delete")
+
+// Revision represents a historical value of an option.
+type Revision struct {
+	ID        int64
+	Namespace string
+	Key       string
+	Value     string
+	Version   int64
+	Action    string
+	// Secret revisions are stored encrypted like the option they belong to
+	Secret    bool
+	KeyID     string
+	dataKey   string
+	CreatedAt time.Time
+}
+
+// revisionColumns lists the columns scanned by scanRevision.
+const revisionColumns = "id, namespace, option_key, value, version, action, secret, key_id, data_key, created_at"
+
+// recordRevision appends a revision for the given option change and queues
+// the change for the change feed. The value is stored as it is stored in the
+// options table, nil for a delete.
+func (r *OptionRepository) recordRevision(key string, value *sealedValue, version int64, action string) error {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `INSERT INTO option_revisions (namespace, option_key, value, version, action, secret, key_id, data_key, created_at, txid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, pg_current_xact_id()::text::bigint)
+		RETURNING id`
+	} else {
+		query = `INSERT INTO option_revisions (namespace, option_key, value, version, action, secret, key_id, data_key, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`
+	}
+
+	// A delete stores no value
+	if value == nil {
+		value = &sealedValue{}
+	}
+
+	change := Change{
+		Namespace: r.namespace,
+		Key:       key,
+		Action:    action,
+	}
+
+	if r.trail != nil {
+		oldValueHash, err := r.auditValueHash(key)
+		if err != nil {
+			return err
+		}
+		change.oldValueHash = oldValueHash
+		change.newValueHash = sealedValueHash(value, action)
+	}
+
+	err := r.db.QueryRow(
+		query,
+		r.namespace,
+		key,
+		nullString(value.Value),
+		version,
+		action,
+		value.Secret,
+		nullString(value.KeyID),
+		nullString(value.DataKey),
+		time.Now().UTC(),
+	).Scan(&change.Revision)
+	if err != nil {
+		return err
+	}
+
+	return r.queueChange(change)
+}
+
+// scanRevision scans a revision row selected with revisionColumns. The value
+// of a secret revision is left encrypted until openRevision.
+func scanRevision(row interface{ Scan(...interface{}) error }) (*Revision, error) {
+	revision := &Revision{}
+	var value, keyID, dataKey sql.NullString
+
+	err := row.Scan(
+		&revision.ID,
+		&revision.Namespace,
+		&revision.Key,
+		&value,
+		&revision.Version,
+		&revision.Action,
+		&revision.Secret,
+		&keyID,
+		&dataKey,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Value = value.String
+	revision.KeyID = keyID.String
+	revision.dataKey = dataKey.String
+	return revision, nil
+}
+
+// History retrieves the revisions of an option, newest first.
+func (r *OptionRepository) History(key string, limit int) ([]*Revision, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT ` + revisionColumns + `
+		FROM option_revisions
+		WHERE namespace = $1 AND option_key = $2
+		ORDER BY id DESC
+		LIMIT $3`
+	} else {
+		query = `SELECT ` + revisionColumns + `
+		FROM option_revisions
+		WHERE namespace = ? AND option_key = ?
+		ORDER BY id DESC
+		LIMIT ?`
+	}
+
+	rows, err := r.db.Query(query, r.namespace, key, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*Revision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.openRevision(revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetRevision retrieves a single revision of an option.
+func (r *OptionRepository) GetRevision(key string, id int64) (*Revision, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT ` + revisionColumns + `
+		FROM option_revisions
+		WHERE namespace = $1 AND option_key = $2 AND id = $3`
+	} else {
+		query = `SELECT ` + revisionColumns + `
+		FROM option_revisions
+		WHERE namespace = ? AND option_key = ? AND id = ?`
+	}
+
+	revision, err := scanRevision(r.db.QueryRow(query, r.namespace, key, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.openRevision(revision); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// GetAt retrieves the revision of an option that was current at the given time.
+// It returns nil if the option did not exist at that time.
+func (r *OptionRepository) GetAt(key string, at time.Time) (*Revision, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT ` + revisionColumns + `
+		FROM option_revisions
+		WHERE namespace = $1 AND option_key = $2 AND created_at <= $3
+		ORDER BY id DESC
+		LIMIT 1`
+	} else {
+		query = `SELECT ` + revisionColumns + `
+		FROM option_revisions
+		WHERE namespace = ? AND option_key = ? AND created_at <= ?
+		ORDER BY id DESC
+		LIMIT 1`
+	}
+
+	revision, err := scanRevision(r.db.QueryRow(query, r.namespace, key, at.UTC()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if revision.Action == RevisionActionDelete {
+		return nil, nil
+	}
+	if err := r.openRevision(revision); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// revisionsSettled keeps the revisions of postgres transactions older than any
+// transaction in progress. Later ones may still be joined by revisions with a
+// lower id, as ids are handed out before transactions commit.
+const revisionsSettled = "txid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint"
+
+// RevisionsSince retrieves the revisions of the namespace recorded after the
+// given revision id, oldest first. An empty key matches every option.
+//
+// sqlite commits one transaction at a time, so revisions are ordered by id.
+// On postgres they are ordered by transaction then id, and revisions of
+// transactions which may still be joined by others are held back until they
+// settle, so no revision is ever skipped. The position of the given revision
+// in that order is looked up from its transaction id.
+func (r *OptionRepository) RevisionsSince(afterID int64, key string, limit int) ([]*Revision, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT ` + revisionColumns + `
+		FROM option_revisions
+		WHERE namespace = $1 AND ($2::text = '' OR option_key = $2::text)
+			AND (txid, id) > (COALESCE((SELECT txid FROM option_revisions WHERE id <= $3 ORDER BY id DESC LIMIT 1), 0), $3)
+			AND ` + revisionsSettled + `
+		ORDER BY txid, id
+		LIMIT $4`
+	} else {
+		query = `SELECT ` + revisionColumns + `
+		FROM option_revisions
+		WHERE namespace = ?1 AND (?2 = '' OR option_key = ?2) AND id > ?3
+		ORDER BY id
+		LIMIT ?4`
+	}
+
+	rows, err := r.db.Query(query, r.namespace, key, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*Revision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.openRevision(revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// LatestRevisionID returns the id of the most recent revision of any option,
+// or zero if there is none. On postgres it is the most recent settled one, so
+// RevisionsSince returns every revision that has not settled yet.
+func (r *OptionRepository) LatestRevisionID() (int64, error) {
+	query := "SELECT COALESCE(MAX(id), 0) FROM option_revisions"
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT COALESCE((
+			SELECT id FROM option_revisions WHERE ` + revisionsSettled + `
+			ORDER BY txid DESC, id DESC
+			LIMIT 1), 0)`
+	}
+
+	var id int64
+	err := r.db.QueryRow(query).Scan(&id)
+	return id, err
+}
+
+// Revert restores the value of a prior revision. The restored value is
+// written as a new revision so the history is never rewritten. A live option
+// keeps its current secrecy. The restored value must conform to the current
+// schema of the key, otherwise a *SchemaViolationError is returned.
+func (r *OptionRepository) Revert(key string, id int64) (*Option, error) {
+	var option *Option
+
+	err := r.Transaction(func(tx *OptionRepository) error {
+		revision, err := tx.GetRevision(key, id)
+		if err != nil {
+			return err
+		}
+		if revision == nil {
+			return ErrRevisionNotFound
+		}
+		if revision.Action == RevisionActionDelete {
+			return ErrRevisionDeleted
+		}
+
+		if err := tx.Schemas().Validate(key, revision.Value); err != nil {
+			return err
+		}
+
+		// The option keeps being secret or not, so reverting to a revision
+		// written before the option became secret does not expose its value
+		secret := revision.Secret
+		current, err := tx.Get(key)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			secret = current.Secret
+		}
+
+		option, _, err = tx.WithSecret(secret).Upsert(key, revision.Value, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return option, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitRevert(t *testing.T) {
+	t.Run("Keeps the option secret", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+
+		_, _, err := repo.Upsert("db.password", `"plain"`, nil)
+		require.NoError(t, err)
+		history, err := repo.History("db.password", 1)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+
+		_, _, err = repo.WithSecret(true).Upsert("db.password", `"s3cret"`, nil)
+		require.NoError(t, err)
+
+		option, err := repo.Revert("db.password", history[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, `"plain"`, option.Value)
+		assert.True(t, option.Secret)
+	})
+
+	t.Run("Validates the value against the current schema", func(t *testing.T) {
+		repo := NewOptionRepository(newTestDB(t))
+
+		_, _, err := repo.Upsert("app.port", `"8080"`, nil)
+		require.NoError(t, err)
+		history, err := repo.History("app.port", 1)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+
+		_, _, err = repo.Upsert("app.port", `8080`, nil)
+		require.NoError(t, err)
+		_, err = repo.Schemas().Save("app.port", `{"type": "integer"}`)
+		require.NoError(t, err)
+
+		var violation *SchemaViolationError
+		_, err = repo.Revert("app.port", history[0].ID)
+		assert.ErrorAs(t, err, &violation)
+
+		option, err := repo.Get("app.port")
+		assert.NoError(t, err)
+		assert.Equal(t, `8080`, option.Value)
+	})
+}
+
+func TestIntegrationRevisionsSince(t *testing.T) {
+	t.Run("Holds back revisions until older transactions commit", func(t *testing.T) {
+		repo := NewOptionRepository(newPostgresTestDB(t)).
+			WithNamespace(fmt.Sprintf("test-%d", time.Now().UnixNano()))
+
+		cursor, err := repo.LatestRevisionID()
+		require.NoError(t, err)
+
+		written := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan error, 1)
+
+		// The first transaction takes the lower revision id and commits last
+		go func() {
+			done <- repo.Transaction(func(tx *OptionRepository) error {
+				if _, _, err := tx.Upsert("app.first", `"a"`, nil); err != nil {
+					close(written)
+					return err
+				}
+				close(written)
+				<-release
+				return nil
+			})
+		}()
+
+		<-written
+		_, _, err = repo.Upsert("app.second", `"b"`, nil)
+		require.NoError(t, err)
+
+		revisions, err := repo.RevisionsSince(cursor, "", 10)
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+
+		close(release)
+		require.NoError(t, <-done)
+
+		revisions, err = repo.RevisionsSince(cursor, "", 10)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, "app.first", revisions[0].Key)
+		assert.Equal(t, "app.second", revisions[1].Key)
+		assert.Less(t, revisions[0].ID, revisions[1].ID)
+
+		revisions, err = repo.RevisionsSince(revisions[1].ID, "", 10)
+		require.NoError(t, err)
+		assert.Empty(t, revisions)
+	})
+}
@@ -0,0 +1,233 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/clivern/zewi/service"
+)
+
+// Schema represents a JSON Schema that option values under a key prefix must conform to.
+type Schema struct {
+	ID        int64
+	Namespace string
+	Prefix    string
+	Schema    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// schemaColumns lists the columns scanned by scanSchema.
+const schemaColumns = "id, namespace, prefix, schema, created_at, updated_at"
+
+// scanSchema scans a schema row selected with schemaColumns.
+func scanSchema(row interface{ Scan(...interface{}) error }) (*Schema, error) {
+	schema := &Schema{}
+
+	err := row.Scan(
+		&schema.ID,
+		&schema.Namespace,
+		&schema.Prefix,
+		&schema.Schema,
+		&schema.CreatedAt,
+		&schema.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// SchemaViolationError is returned when an option value does not conform to
+// the schema of its key.
+type SchemaViolationError struct {
+	Key        string
+	Schema     *Schema
+	Violations []service.SchemaViolation
+}
+
+// Error implements the error interface.
+func (e *SchemaViolationError) Error() string {
+	return fmt.Sprintf("value of option %s does not conform to schema %s", e.Key, e.Schema.Prefix)
+}
+
+// SchemaRepository handles database operations for option schemas.
+type SchemaRepository struct {
+	db        querier
+	driver    string
+	namespace string
+}
+
+// NewSchemaRepository creates a new schema repository scoped to the default namespace.
+func NewSchemaRepository(db *sql.DB) *SchemaRepository {
+	return &SchemaRepository{
+		db:        db,
+		driver:    GetDriver(),
+		namespace: DefaultNamespace,
+	}
+}
+
+// WithNamespace returns a copy of the repository scoped to the given namespace.
+func (r *SchemaRepository) WithNamespace(namespace string) *SchemaRepository {
+	return &SchemaRepository{
+		db:        r.db,
+		driver:    r.driver,
+		namespace: namespace,
+	}
+}
+
+// Namespace returns the namespace the repository is scoped to.
+func (r *SchemaRepository) Namespace() string {
+	return r.namespace
+}
+
+// Save creates or replaces the schema registered for a key prefix.
+func (r *SchemaRepository) Save(prefix, schema string) (*Schema, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `INSERT INTO option_schemas (namespace, prefix, schema, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (namespace, prefix) DO UPDATE SET
+			schema = EXCLUDED.schema,
+			updated_at = EXCLUDED.updated_at
+		RETURNING ` + schemaColumns
+	} else {
+		query = `INSERT INTO option_schemas (namespace, prefix, schema, created_at, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?4)
+		ON CONFLICT (namespace, prefix) DO UPDATE SET
+			schema = excluded.schema,
+			updated_at = excluded.updated_at
+		RETURNING ` + schemaColumns
+	}
+
+	return scanSchema(r.db.QueryRow(query, r.namespace, prefix, schema, time.Now().UTC()))
+}
+
+// Get retrieves the schema registered for a key prefix.
+func (r *SchemaRepository) Get(prefix string) (*Schema, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = "SELECT " + schemaColumns + " FROM option_schemas WHERE namespace = $1 AND prefix = $2"
+	} else {
+		query = "SELECT " + schemaColumns + " FROM option_schemas WHERE namespace = ? AND prefix = ?"
+	}
+
+	schema, err := scanSchema(r.db.QueryRow(query, r.namespace, prefix))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Match retrieves the schema with the longest prefix of the given option key.
+func (r *SchemaRepository) Match(key string) (*Schema, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT ` + schemaColumns + `
+		FROM option_schemas
+		WHERE namespace = $1 AND substr($2, 1, length(prefix)) = prefix
+		ORDER BY length(prefix) DESC
+		LIMIT 1`
+	} else {
+		query = `SELECT ` + schemaColumns + `
+		FROM option_schemas
+		WHERE namespace = ? AND substr(?, 1, length(prefix)) = prefix
+		ORDER BY length(prefix) DESC
+		LIMIT 1`
+	}
+
+	schema, err := scanSchema(r.db.QueryRow(query, r.namespace, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// List retrieves all schemas in the namespace ordered by prefix.
+func (r *SchemaRepository) List() ([]*Schema, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = "SELECT " + schemaColumns + " FROM option_schemas WHERE namespace = $1 ORDER BY prefix"
+	} else {
+		query = "SELECT " + schemaColumns + " FROM option_schemas WHERE namespace = ? ORDER BY prefix"
+	}
+
+	rows, err := r.db.Query(query, r.namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []*Schema
+	for rows.Next() {
+		schema, err := scanSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+
+	return schemas, rows.Err()
+}
+
+// Delete removes the schema registered for a key prefix. It reports whether a
+// schema was removed.
+func (r *SchemaRepository) Delete(prefix string) (bool, error) {
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = "DELETE FROM option_schemas WHERE namespace = $1 AND prefix = $2"
+	} else {
+		query = "DELETE FROM option_schemas WHERE namespace = ? AND prefix = ?"
+	}
+
+	result, err := r.db.Exec(query, r.namespace, prefix)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// Validate checks a value against the schema with the longest prefix of the
+// option key, and values of feature flags against the flag definition. It
+// returns a *SchemaViolationError if the value does not conform.
+func (r *SchemaRepository) Validate(key, value string) error {
+	if err := r.validateFlag(key, value); err != nil {
+		return err
+	}
+
+	schema, err := r.Match(key)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	violations, err := service.ValidateSchema(schema.Schema, value)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		return &SchemaViolationError{
+			Key:        key,
+			Schema:     schema,
+			Violations: violations,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/clivern/zewi/service"
+)
+
+// MaskedValue is the JSON document shown in place of a secret value that was
+// not explicitly revealed.
+const MaskedValue = `"********"`
+
+// ErrEncryptionDisabled is returned when a secret value is written or read
+// without a configured keyring.
+var ErrEncryptionDisabled = errors.New("encryption is not configured")
+
+var (
+	// globalKeyring holds the keys encrypting secret option values
+	globalKeyring *service.Keyring
+	// keyringMu protects globalKeyring
+	keyringMu sync.RWMutex
+)
+
+// SetKeyring sets the keyring used by new repositories to encrypt and decrypt
+// secret option values. A nil keyring disables encryption.
+func SetKeyring(keyring *service.Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+
+	globalKeyring = keyring
+}
+
+// GetKeyring returns the configured keyring, or nil if encryption is disabled.
+func GetKeyring() *service.Keyring {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+
+	return globalKeyring
+}
+
+// sealedValue is an option value as stored in the database. The value of a
+// secret option is a JSON string holding the ciphertext, and the data key it
+// was encrypted with is stored wrapped by the key encryption key KeyID.
+type sealedValue struct {
+	Value   string
+	Secret  bool
+	KeyID   string
+	DataKey string
+}
+
+// DisplayValue returns the value of the option, or MaskedValue if the option
+// is secret and not revealed.
+func (o *Option) DisplayValue(reveal bool) string {
+	if o.Secret && !reveal {
+		return MaskedValue
+	}
+	return o.Value
+}
+
+// DisplayValue returns the value of the revision, or MaskedValue if the
+// revision is secret and not revealed.
+func (r *Revision) DisplayValue(reveal bool) string {
+	if r.Secret && !reveal {
+		return MaskedValue
+	}
+	return r.Value
+}
+
+// additionalData binds a ciphertext to the option it belongs to, so it can
+// not be moved to another option.
+func additionalData(namespace, key string) []byte {
+	return []byte(namespace + "/" + key)
+}
+
+// isSecret reports whether the values written to the option must be
+// encrypted. Unless the repository marks values explicitly, the option
+// keeps being secret or not.
+func (r *OptionRepository) isSecret(key string) (bool, error) {
+	if r.secret != nil {
+		return *r.secret, nil
+	}
+
+	var query string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		query = `SELECT secret FROM options
+		WHERE namespace = $1 AND key = $2 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $3)`
+	} else {
+		query = `SELECT secret FROM options
+		WHERE namespace = ? AND key = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`
+	}
+
+	var secret bool
+	err := r.db.QueryRow(query, r.namespace, key, time.Now().UTC()).Scan(&secret)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return secret, err
+}
+
+// sealValue encrypts the value of a secret option.
+func (r *OptionRepository) sealValue(key, value string) (*sealedValue, error) {
+	secret, err := r.isSecret(key)
+	if err != nil {
+		return nil, err
+	}
+	if !secret {
+		return &sealedValue{Value: value}, nil
+	}
+
+	if r.keyring == nil {
+		return nil, ErrEncryptionDisabled
+	}
+
+	keyID, dataKey, ciphertext, err := r.keyring.Seal([]byte(value), additionalData(r.namespace, key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt value: %w", err)
+	}
+
+	encoded, err := json.Marshal(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sealedValue{
+		Value:   string(encoded),
+		Secret:  true,
+		KeyID:   keyID,
+		DataKey: dataKey,
+	}, nil
+}
+
+// openValue decrypts the value of a secret option.
+func (r *OptionRepository) openValue(namespace, key string, sealed *sealedValue) (string, error) {
+	if !sealed.Secret {
+		return sealed.Value, nil
+	}
+
+	if r.keyring == nil {
+		return "", ErrEncryptionDisabled
+	}
+
+	var ciphertext string
+	if err := json.Unmarshal([]byte(sealed.Value), &ciphertext); err != nil {
+		return "", fmt.Errorf("malformed secret value of option %s", key)
+	}
+
+	plaintext, err := r.keyring.Open(sealed.KeyID, sealed.DataKey, ciphertext, additionalData(namespace, key))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// openOption decrypts the value of an option scanned with scanOption in place.
+func (r *OptionRepository) openOption(option *Option) error {
+	value, err := r.openValue(option.Namespace, option.Key, option.sealed())
+	if err != nil {
+		return err
+	}
+
+	option.Value = value
+	return nil
+}
+
+// openRevision decrypts the value of a revision scanned with scanRevision in place.
+func (r *OptionRepository) openRevision(revision *Revision) error {
+	if revision.Action == RevisionActionDelete {
+		return nil
+	}
+
+	value, err := r.openValue(revision.Namespace, revision.Key, &sealedValue{
+		Value:   revision.Value,
+		Secret:  revision.Secret,
+		KeyID:   revision.KeyID,
+		DataKey: revision.dataKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	revision.Value = value
+	return nil
+}
+
+// RotateKeys wraps the data keys of every secret option and revision that
+// are not wrapped by the active key again with it. Values are not encrypted
+// again. It returns the number of rewrapped rows.
+func (r *OptionRepository) RotateKeys() (int, error) {
+	if r.keyring == nil {
+		return 0, ErrEncryptionDisabled
+	}
+
+	var selectQuery, updateQuery string
+	if r.driver == "postgres" || r.driver == "postgresql" {
+		selectQuery = "SELECT id, key_id, data_key FROM %s WHERE secret AND key_id <> $1"
+		updateQuery = "UPDATE %s SET key_id = $1, data_key = $2 WHERE id = $3"
+	} else {
+		selectQuery = "SELECT id, key_id, data_key FROM %s WHERE secret AND key_id <> ?"
+		updateQuery = "UPDATE %s SET key_id = ?, data_key = ? WHERE id = ?"
+	}
+
+	rotated := 0
+	err := r.Transaction(func(tx *OptionRepository) error {
+		for _, table := range []string{"options", "option_revisions"} {
+			rows, err := tx.db.Query(fmt.Sprintf(selectQuery, table), r.keyring.ActiveKeyID())
+			if err != nil {
+				return err
+			}
+
+			type wrappedKey struct {
+				id      int64
+				keyID   string
+				dataKey string
+			}
+
+			var items []wrappedKey
+			for rows.Next() {
+				var item wrappedKey
+				if err := rows.Scan(&item.id, &item.keyID, &item.dataKey); err != nil {
+					rows.Close()
+					return err
+				}
+				items = append(items, item)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			for _, item := range items {
+				keyID, dataKey, err := r.keyring.Rewrap(item.keyID, item.dataKey)
+				if err != nil {
+					return fmt.Errorf("failed to rewrap data key of %s row %d: %w", table, item.id, err)
+				}
+
+				if _, err := tx.db.Exec(fmt.Sprintf(updateQuery, table), keyID, dataKey, item.id); err != nil {
+					return err
+				}
+			}
+
+			rotated += len(items)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rotated, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+// Package testsupport provides the databases and keys shared by the tests of
+// the other packages. It does not import db so the tests of db can use it.
+package testsupport
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/clivern/zewi/migration"
+	"github.com/clivern/zewi/service"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+
+	"github.com/stretchr/testify/require"
+)
+
+// SQLite creates a sqlite database with every migration applied in a
+// directory removed when the test ends, and returns its data source
+func SQLite(t testing.TB) string {
+	t.Helper()
+
+	dataSource := filepath.Join(t.TempDir(), "zewi.db") + "?_busy_timeout=5000"
+
+	conn, err := sql.Open("sqlite3", dataSource)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	Migrate(t, conn, "sqlite")
+
+	return dataSource
+}
+
+// Migrate applies every migration shipped with the binary to the database
+func Migrate(t testing.TB, conn *sql.DB, driver string) {
+	t.Helper()
+
+	mgr := migration.NewManager(conn, driver)
+	for _, m := range migration.GetAll() {
+		mgr.Register(m)
+	}
+	require.NoError(t, mgr.RegisterSQL(migration.Files()))
+	require.NoError(t, mgr.Up())
+}
+
+// Keyring returns a keyring encrypting secret options with a fixed key
+func Keyring(t testing.TB) *service.Keyring {
+	t.Helper()
+
+	keyring, err := service.ParseKeyring("k1:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	require.NoError(t, err)
+	return keyring
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/clivern/zewi/db"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ActorKey is the context key for storing the authenticated actor
+const ActorKey contextKey = "actor"
+
+// actorHolderKey is the context key for the holder Audit reads the actor from
+const actorHolderKey contextKey = "actor_holder"
+
+// anonymousActor is recorded for requests without an authenticated actor
+const anonymousActor = "anonymous"
+
+// actorHolder receives the actor authenticated by a middleware running after
+// Audit, so Audit can record it once the request is handled
+type actorHolder struct {
+	actor string
+}
+
+// WithActor returns a copy of the context carrying the authenticated actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	if holder, ok := ctx.Value(actorHolderKey).(*actorHolder); ok {
+		holder.actor = actor
+	}
+	return context.WithValue(ctx, ActorKey, actor)
+}
+
+// GetActor retrieves the authenticated actor from the context
+func GetActor(ctx context.Context) string {
+	if actor, ok := ctx.Value(ActorKey).(string); ok && actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// auditTrailKey is the context key for the audit trail of the request
+const auditTrailKey contextKey = "audit_trail"
+
+// GetAuditTrail retrieves the audit trail collecting the option changes of
+// the request, or nil if the request is not audited
+func GetAuditTrail(ctx context.Context) *db.AuditTrail {
+	if trail, ok := ctx.Value(auditTrailKey).(*db.AuditTrail); ok {
+		return trail
+	}
+	return nil
+}
+
+// Audit records an audit event for every mutating request. It must run after
+// routing so the namespace, option key and flag URL parameters are resolved.
+// The actor is read once the request is handled, so authentication may run
+// before or after Audit. Handlers attach the trail from GetAuditTrail to the
+// repositories they write with, and an event is recorded per changed option.
+func Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if db.GetDB() == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		event := &db.AuditEvent{
+			RequestID: GetRequestID(r.Context()),
+			RemoteIP:  RemoteIP(r),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Namespace: chi.URLParam(r, "namespace"),
+			Key:       chi.URLParam(r, "key"),
+		}
+
+		if event.Namespace == "" {
+			event.Namespace = db.DefaultNamespace
+		}
+
+		// Feature flags are options under the flag prefix
+		if flag := chi.URLParam(r, "flag"); flag != "" {
+			event.Key = db.FlagKey(flag)
+		}
+
+		wrapped := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+
+		holder := &actorHolder{}
+		trail := db.NewAuditTrail()
+
+		ctx := context.WithValue(r.Context(), actorHolderKey, holder)
+		ctx = context.WithValue(ctx, auditTrailKey, trail)
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		event.Actor = GetActor(r.Context())
+		if holder.actor != "" {
+			event.Actor = holder.actor
+		}
+
+		event.Status = wrapped.statusCode
+		RecordAudit(event, trail)
+	})
+}
+
+// RecordAudit records an event for every option change collected by the
+// trail, or the event alone if no option changed. The result is derived from
+// the status of the event.
+func RecordAudit(event *db.AuditEvent, trail *db.AuditTrail) {
+	database := db.GetDB()
+	if database == nil {
+		return
+	}
+
+	event.Result = db.AuditResultSuccess
+	if event.Status >= http.StatusBadRequest {
+		event.Result = db.AuditResultFailure
+	}
+
+	events := []*db.AuditEvent{event}
+	if changes := trail.Changes(); len(changes) > 0 {
+		events = make([]*db.AuditEvent, 0, len(changes))
+		for _, change := range changes {
+			item := *event
+			item.Namespace = change.Namespace
+			item.Key = change.Key
+			item.OldValueHash = change.OldValueHash
+			item.NewValueHash = change.NewValueHash
+			events = append(events, &item)
+		}
+	}
+
+	repo := db.NewAuditRepository(database)
+	for _, item := range events {
+		if err := repo.Record(item); err != nil {
+			log.Error().
+				Err(err).
+				Str("request_id", item.RequestID).
+				Str("method", item.Method).
+				Str("path", item.Path).
+				Msg("Failed to record audit event")
+		}
+	}
+}
+
+// RemoteIP returns the IP address of the client
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clivern/zewi/db"
+	"github.com/clivern/zewi/internal/testsupport"
+	"github.com/clivern/zewi/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDB initializes the global connection with a migrated sqlite
+// database, closed when the test ends
+func newTestDB(t *testing.T) {
+	t.Helper()
+
+	err := db.InitDB(db.Config{Driver: "sqlite", DataSource: testsupport.SQLite(t)})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.CloseDB() })
+}
+
+func TestUnitAudit(t *testing.T) {
+	handler := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	for name, mount := range map[string]func(r chi.Router){
+		"Records the actor authenticated after Audit": func(r chi.Router) {
+			r.Use(Audit)
+			r.Use(BasicAuth("admin", "secret"))
+			r.Put("/options/{key}", handler)
+		},
+		"Records the actor authenticated before Audit": func(r chi.Router) {
+			r.Use(BasicAuth("admin", "secret"))
+			r.Use(Audit)
+			r.Put("/options/{key}", handler)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			newTestDB(t)
+
+			router := chi.NewRouter()
+			router.Group(mount)
+
+			req := httptest.NewRequest(http.MethodPut, "/options/app.name", nil)
+			req.SetBasicAuth("admin", "secret")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+
+			events, err := db.NewAuditRepository(db.GetDB()).Find(db.AuditFilter{})
+			assert.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, "admin", events[0].Actor)
+			assert.Equal(t, "app.name", events[0].Key)
+			assert.Equal(t, db.AuditResultSuccess, events[0].Result)
+		})
+	}
+
+	t.Run("Records anonymous requests", func(t *testing.T) {
+		newTestDB(t)
+
+		router := chi.NewRouter()
+		router.With(Audit).Delete("/options/{key}", handler)
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/options/app.name", nil))
+
+		events, err := db.NewAuditRepository(db.GetDB()).Find(db.AuditFilter{})
+		assert.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, anonymousActor, events[0].Actor)
+	})
+
+	t.Run("Records every option the request changed", func(t *testing.T) {
+		newTestDB(t)
+		db.SetKeyring(testsupport.Keyring(t))
+		defer db.SetKeyring(nil)
+
+		repo := db.NewOptionRepository(db.GetDB())
+		_, _, err := repo.Upsert("app.name", `"zewi"`, nil)
+		require.NoError(t, err)
+
+		router := chi.NewRouter()
+		router.With(Audit).Post("/options/batch", func(w http.ResponseWriter, r *http.Request) {
+			repo := db.NewOptionRepository(db.GetDB()).WithAuditTrail(GetAuditTrail(r.Context()))
+			err := repo.Transaction(func(tx *db.OptionRepository) error {
+				if _, _, err := tx.Upsert("app.name", `"zewi 2"`, nil); err != nil {
+					return err
+				}
+				_, _, err := tx.WithSecret(true).Upsert("app.password", `"s3cret"`, nil)
+				return err
+			})
+			assert.NoError(t, err)
+			w.WriteHeader(http.StatusOK)
+		})
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/options/batch", nil))
+
+		events, err := db.NewAuditRepository(db.GetDB()).Find(db.AuditFilter{})
+		assert.NoError(t, err)
+		require.Len(t, events, 2)
+
+		// Newest first
+		assert.Equal(t, "app.password", events[0].Key)
+		assert.Empty(t, events[0].OldValueHash)
+		assert.Empty(t, events[0].NewValueHash)
+
+		assert.Equal(t, "app.name", events[1].Key)
+		assert.Equal(t, service.HashValue(`"zewi"`), events[1].OldValueHash)
+		assert.Equal(t, service.HashValue(`"zewi 2"`), events[1].NewValueHash)
+	})
+}
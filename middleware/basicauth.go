@@ -26,7 +26,7 @@ func BasicAuth(username, password string) func(http.Handler) http.Handler {
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(WithActor(r.Context(), user)))
 		})
 	}
 }
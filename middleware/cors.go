@@ -14,7 +14,8 @@ func CORS(next http.Handler) http.Handler {
 		// Set CORS headers to allow all origins
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, X-Request-ID, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Total-Count, X-Request-ID")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
 		// Handle preflight requests
@@ -5,6 +5,8 @@
 package middleware
 
 import (
+	"bufio"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
@@ -48,6 +50,31 @@ var (
 		},
 		[]string{"method", "path", "status"},
 	)
+
+	// WebSocketConnections is the number of connected WebSocket clients
+	WebSocketConnections = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "websocket_connections",
+			Help: "Number of connected WebSocket clients",
+		},
+	)
+
+	// WebSocketMessagesTotal counts WebSocket messages by direction, in or out
+	WebSocketMessagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "websocket_messages_total",
+			Help: "Total number of WebSocket messages",
+		},
+		[]string{"direction"},
+	)
+
+	// WebSocketSlowConsumersTotal counts clients disconnected for not keeping up
+	WebSocketSlowConsumersTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "websocket_slow_consumers_total",
+			Help: "Total number of WebSocket clients disconnected for not keeping up with messages",
+		},
+	)
 )
 
 // metricsResponseWriter wraps http.ResponseWriter to capture metrics
@@ -72,6 +99,21 @@ func (mrw *metricsResponseWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Unwrap returns the underlying writer so http.ResponseController can reach it
+func (mrw *metricsResponseWriter) Unwrap() http.ResponseWriter {
+	return mrw.ResponseWriter
+}
+
+// Hijack lets protocol upgrades such as WebSocket take over the connection
+func (mrw *metricsResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(mrw.ResponseWriter).Hijack()
+	if err == nil {
+		mrw.statusCode = http.StatusSwitchingProtocols
+		mrw.wroteHeader = true
+	}
+	return conn, buf, err
+}
+
 // PrometheusMiddleware creates a middleware for Prometheus metrics
 func PrometheusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
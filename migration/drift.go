@@ -0,0 +1,216 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"crypto/sha256"
+	_ "embed" // Source of the Go migrations
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// registrySource is the source of the Go migrations, so their checksums cover
+// the code of their functions like the checksums of SQL migrations cover their
+// scripts
+//
+//go:embed registry.go
+var registrySource []byte
+
+// Kinds of drift between the applied and the registered migrations
+const (
+	// driftModified is an applied migration whose content changed since
+	driftModified = "modified"
+	// driftMissing is an applied migration that is not registered anymore
+	driftMissing = "missing"
+	// driftOutOfOrder is a pending migration older than an applied one
+	driftOutOfOrder = "out of order"
+)
+
+// drift is a difference between the applied and the registered migrations
+type drift struct {
+	version string
+	kind    string
+}
+
+// appliedMigration is a row of the migrations table
+type appliedMigration struct {
+	version     string
+	description string
+	// checksum is empty for migrations applied before checksums were
+	// recorded and for migrations registered without one
+	checksum string
+}
+
+// checksum hashes the up and down scripts or functions of a migration
+func checksum(up, down string) string {
+	hash := sha256.Sum256([]byte(up + "\x00" + down))
+	return hex.EncodeToString(hash[:])
+}
+
+// goChecksums sets the checksum of Go migrations to the hash of the source of
+// their up and down functions, which must be declared in source
+func goChecksums(migrations []Migration, source []byte) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "registry.go", source, 0)
+	if err != nil {
+		return err
+	}
+
+	functions := map[string]string{}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			functions[fn.Name.Name] = string(source[fset.Position(fn.Pos()).Offset:fset.Position(fn.End()).Offset])
+		}
+	}
+
+	for i := range migrations {
+		up, err := functionSource(functions, migrations[i].Up)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migrations[i].Version, err)
+		}
+
+		down, err := functionSource(functions, migrations[i].Down)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migrations[i].Version, err)
+		}
+
+		migrations[i].Checksum = checksum(up, down)
+	}
+
+	return nil
+}
+
+// functionSource returns the source of a migration function, empty if nil
+func functionSource(functions map[string]string, fn func(Executor) error) (string, error) {
+	if fn == nil {
+		return "", nil
+	}
+
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	source, ok := functions[name]
+	if !ok {
+		return "", fmt.Errorf("function %s is not declared in registry.go", name)
+	}
+	return source, nil
+}
+
+// addChecksumColumn adds the checksum column to a migrations table created
+// before checksums were recorded
+func (m *Manager) addChecksumColumn() error {
+	var query string
+
+	switch m.driverName() {
+	case "sqlite":
+		var count int
+		err := m.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('migrations') WHERE name = 'checksum'").Scan(&count)
+		if err != nil || count > 0 {
+			return err
+		}
+		query = "ALTER TABLE migrations ADD COLUMN checksum VARCHAR(64)"
+	case "postgres":
+		query = "ALTER TABLE migrations ADD COLUMN IF NOT EXISTS checksum VARCHAR(64)"
+	}
+
+	_, err := m.db.Exec(query)
+	return err
+}
+
+// appliedMigrations returns the applied migrations by version
+func (m *Manager) appliedMigrations() (map[string]appliedMigration, error) {
+	history, err := m.history()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]appliedMigration, len(history))
+	for _, record := range history {
+		applied[record.version] = record
+	}
+	return applied, nil
+}
+
+// drift compares the applied migrations with the registered ones, which must
+// be sorted by version. Checksums are only compared when both are known.
+func (m *Manager) drift(applied map[string]appliedMigration) []drift {
+	var drifts []drift
+	var latest string
+
+	registered := map[string]bool{}
+	for _, migration := range m.migrations {
+		registered[migration.Version] = true
+	}
+
+	for version := range applied {
+		if version > latest {
+			latest = version
+		}
+		if !registered[version] {
+			drifts = append(drifts, drift{version: version, kind: driftMissing})
+		}
+	}
+
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+
+		switch {
+		case !ok && migration.Version < latest:
+			drifts = append(drifts, drift{version: migration.Version, kind: driftOutOfOrder})
+		case ok && record.checksum != "" && migration.Checksum != "" && record.checksum != migration.Checksum:
+			drifts = append(drifts, drift{version: migration.Version, kind: driftModified})
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].version < drifts[j].version
+	})
+
+	return drifts
+}
+
+// driftError describes the drift refusing migrate up
+func driftError(drifts []drift) error {
+	descriptions := make([]string, 0, len(drifts))
+	for _, d := range drifts {
+		descriptions = append(descriptions, fmt.Sprintf("%s is %s", d.version, d.kind))
+	}
+
+	return fmt.Errorf(
+		"applied migrations drifted from the registered ones (%s), check migrate status or pass --allow-drift",
+		strings.Join(descriptions, ", "),
+	)
+}
+
+// backfillChecksums records the checksums of applied migrations which have
+// none, trusting their current content
+func (m *Manager) backfillChecksums(applied map[string]appliedMigration) error {
+	var query string
+	if m.driverName() == "postgres" {
+		query = "UPDATE migrations SET checksum = $1 WHERE version = $2 AND checksum IS NULL"
+	} else {
+		query = "UPDATE migrations SET checksum = ? WHERE version = ? AND checksum IS NULL"
+	}
+
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+		if !ok || record.checksum != "" || migration.Checksum == "" {
+			continue
+		}
+
+		if _, err := m.db.Exec(query, migration.Checksum, migration.Version); err != nil {
+			return fmt.Errorf("failed to record checksum of migration %s: %w", migration.Version, err)
+		}
+	}
+
+	return nil
+}
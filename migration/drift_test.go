@@ -0,0 +1,128 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitDrift(t *testing.T) {
+	files := func(up string) fstest.MapFS {
+		return fstest.MapFS{
+			"20250101000001_create_items.sqlite.up.sql":   {Data: []byte(up)},
+			"20250101000001_create_items.sqlite.down.sql": {Data: []byte("DROP TABLE items")},
+		}
+	}
+
+	t.Run("Refuses modified migrations", func(t *testing.T) {
+		db := newEmptyDB(t)
+
+		m := NewManager(db, "sqlite")
+		require.NoError(t, m.RegisterSQL(files("CREATE TABLE items (id INTEGER PRIMARY KEY)")))
+		require.NoError(t, m.Up())
+
+		var calls []string
+		m = NewManager(db, "sqlite")
+		require.NoError(t, m.RegisterSQL(files("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)")))
+		m.Register(tableMigration("20250101000002", &calls))
+
+		err := m.Up()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "20250101000001 is modified")
+		assert.Empty(t, calls)
+
+		m.SetAllowDrift(true)
+		assert.NoError(t, m.Up())
+		assert.Equal(t, []string{"up 20250101000002"}, calls)
+	})
+
+	t.Run("Refuses missing migrations", func(t *testing.T) {
+		db := newEmptyDB(t)
+		var calls []string
+
+		m := NewManager(db, "sqlite")
+		m.Register(tableMigration("001", &calls))
+		require.NoError(t, m.Up())
+
+		m = NewManager(db, "sqlite")
+		m.Register(tableMigration("002", &calls))
+
+		err := m.Up()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "001 is missing")
+		assert.Equal(t, []string{"001"}, appliedVersions(t, db))
+	})
+
+	t.Run("Refuses migrations older than applied ones", func(t *testing.T) {
+		db := newEmptyDB(t)
+		var calls []string
+
+		m := NewManager(db, "sqlite")
+		m.Register(tableMigration("002", &calls))
+		require.NoError(t, m.Up())
+
+		m.Register(tableMigration("001", &calls))
+
+		err := m.Up()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "001 is out of order")
+		assert.Equal(t, []string{"002"}, appliedVersions(t, db))
+	})
+
+	t.Run("Backfills checksums of migrations applied without one", func(t *testing.T) {
+		db := newEmptyDB(t)
+
+		m := NewManager(db, "sqlite")
+		require.NoError(t, m.RegisterSQL(files("CREATE TABLE items (id INTEGER PRIMARY KEY)")))
+		require.NoError(t, m.Up())
+
+		_, err := db.Exec("UPDATE migrations SET checksum = NULL")
+		require.NoError(t, err)
+
+		require.NoError(t, m.Up())
+
+		var checksum string
+		require.NoError(t, db.QueryRow("SELECT checksum FROM migrations WHERE version = '20250101000001'").Scan(&checksum))
+		assert.Equal(t, m.migrations[0].Checksum, checksum)
+	})
+
+	t.Run("Checksums every Go migration", func(t *testing.T) {
+		for _, migration := range GetAll() {
+			assert.NotEmpty(t, migration.Checksum, migration.Version)
+		}
+	})
+
+	t.Run("Refuses edited Go migrations", func(t *testing.T) {
+		db := newEmptyDB(t)
+
+		m := NewManager(db, "sqlite")
+		for _, migration := range GetAll() {
+			m.Register(migration)
+		}
+		require.NoError(t, m.Up())
+
+		// The leases table gets a column after it was applied
+		edited := strings.Replace(string(registrySource), "token VARCHAR(64) NOT NULL,", "token VARCHAR(64) NOT NULL,\n\t\t\tnote TEXT,", 1)
+		require.NotEqual(t, string(registrySource), edited)
+
+		migrations := GetAll()
+		require.NoError(t, goChecksums(migrations, []byte(edited)))
+
+		m = NewManager(db, "sqlite")
+		for _, migration := range migrations {
+			m.Register(migration)
+		}
+
+		err := m.Up()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "20250101000013 is modified")
+		assert.NotContains(t, err.Error(), "20250101000012")
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+
+	"github.com/stretchr/testify/require"
+)
+
+// newEmptyDB opens a sqlite database with no migration applied, closed when
+// the test ends
+func newEmptyDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "zewi.db")+"?_busy_timeout=100")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// tableMigration creates a table named after its version on up and drops it
+// on down, appending the direction and version to calls
+func tableMigration(version string, calls *[]string) Migration {
+	return Migration{
+		Version:     version,
+		Description: "Create table " + version,
+		Up: func(db Executor) error {
+			*calls = append(*calls, "up "+version)
+			_, err := db.Exec("CREATE TABLE t" + version + " (id INTEGER PRIMARY KEY)")
+			return err
+		},
+		Down: func(db Executor) error {
+			*calls = append(*calls, "down "+version)
+			_, err := db.Exec("DROP TABLE t" + version)
+			return err
+		},
+	}
+}
+
+// hasTable reports whether the table exists
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	require.NoError(t, err)
+	return count > 0
+}
+
+// appliedVersions returns the versions recorded as applied, in order
+func appliedVersions(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT version FROM migrations ORDER BY version")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var versions []string
+	for rows.Next() {
+		var version string
+		require.NoError(t, rows.Scan(&version))
+		versions = append(versions, version)
+	}
+	require.NoError(t, rows.Err())
+	return versions
+}
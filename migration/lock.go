@@ -0,0 +1,307 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// advisoryLockKey is the postgres advisory lock key held while migrating. It
+// fits in 32 bits so pg_locks reports it as objid with classid 0.
+const advisoryLockKey = 20250101
+
+// lockLease is how long the sqlite lock row lives without being refreshed, so
+// the lock of a crashed process is taken over
+const lockLease = 30 * time.Second
+
+// lockRetryInterval is the delay between attempts to take a held lock
+const lockRetryInterval = time.Second
+
+// lockLogInterval is the delay between logs of who holds the lock
+const lockLogInterval = 10 * time.Second
+
+// minAdvisoryLockConns is the smallest postgres pool migrations run with, as
+// the advisory lock pins a connection and migrations need another one
+const minAdvisoryLockConns = 2
+
+// locker serializes migrations across processes sharing a database
+type locker interface {
+	// tryLock takes the lock if free, or returns a description of the holder
+	tryLock(ctx context.Context) (bool, string, error)
+	// through keeps the lock up through the transaction of the running
+	// migration, or through the database again once q is nil
+	through(q querier)
+	// unlock releases the lock
+	unlock() error
+}
+
+// lockOwner identifies the process taking the migration lock
+func lockOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// lock takes the migration lock, waiting for it up to the lock timeout, and
+// returns the function releasing it
+func (m *Manager) lock() (func(), error) {
+	var l locker
+
+	switch m.driver {
+	case "sqlite":
+		l = &rowLock{db: m.db, owner: lockOwner()}
+	case "postgres", "postgresql":
+		if conns := m.db.Stats().MaxOpenConnections; conns > 0 && conns < minAdvisoryLockConns {
+			return nil, fmt.Errorf(
+				"migrations need max_open_conns of at least %d on postgres, one connection holds the migration lock",
+				minAdvisoryLockConns,
+			)
+		}
+		l = &advisoryLock{db: m.db, owner: lockOwner()}
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s (supported: sqlite, postgres, postgresql)", m.driver)
+	}
+
+	ctx := context.Background()
+	deadline := time.Now().Add(m.lockTimeout)
+	var lastLog time.Time
+
+	for {
+		locked, holder, err := l.tryLock(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to take migration lock: %w", err)
+		}
+
+		if locked {
+			log.Debug().Msg("Migration lock taken")
+			m.held = l
+
+			return func() {
+				m.held = nil
+				if err := l.unlock(); err != nil {
+					log.Error().Err(err).Msg("Failed to release migration lock")
+					return
+				}
+				log.Debug().Msg("Migration lock released")
+			}, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for migration lock held by %s", m.lockTimeout, holder)
+		}
+
+		if time.Since(lastLog) >= lockLogInterval {
+			log.Info().
+				Str("holder", holder).
+				Dur("timeout", m.lockTimeout).
+				Msg("Waiting for migration lock")
+			lastLog = time.Now()
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// advisoryLock is a postgres session level advisory lock. It is held by a
+// dedicated connection, so postgres releases it if the process dies.
+type advisoryLock struct {
+	db    *sql.DB
+	owner string
+	conn  *sql.Conn
+}
+
+// tryLock takes the advisory lock if free
+func (l *advisoryLock) tryLock(ctx context.Context) (bool, string, error) {
+	if l.conn == nil {
+		conn, err := l.db.Conn(ctx)
+		if err != nil {
+			return false, "", err
+		}
+
+		// Shows the owner in pg_stat_activity to processes waiting for the lock
+		if _, err := conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", "zewi-migrate "+l.owner); err != nil {
+			conn.Close()
+			return false, "", err
+		}
+		l.conn = conn
+	}
+
+	var locked bool
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", advisoryLockKey).Scan(&locked); err != nil {
+		return false, "", err
+	}
+	if locked {
+		return true, "", nil
+	}
+
+	var application, address string
+	var pid int
+	var since time.Time
+
+	err := l.conn.QueryRowContext(ctx, `
+		SELECT COALESCE(a.application_name, ''), COALESCE(host(a.client_addr), 'local'), a.pid, a.backend_start
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.classid = 0 AND l.objid::bigint = $1 AND l.granted
+		LIMIT 1`, advisoryLockKey).Scan(&application, &address, &pid, &since)
+	if err == sql.ErrNoRows {
+		return false, "unknown", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	return false, fmt.Sprintf("%s (pid %d from %s since %s)", application, pid, address, since.UTC().Format(time.RFC3339)), nil
+}
+
+// through does nothing, postgres holds the advisory lock until unlock
+func (l *advisoryLock) through(querier) {}
+
+// unlock releases the advisory lock and its connection
+func (l *advisoryLock) unlock() error {
+	if l.conn == nil {
+		return nil
+	}
+	defer l.conn.Close()
+
+	_, err := l.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+	return err
+}
+
+// rowLock is a lock row in the migration_lock table for sqlite. The holder
+// refreshes the row while migrating, a row not refreshed within lockLease is
+// taken over. While a migration transaction holds the sqlite write lock the
+// row is refreshed through that transaction, as any other write would wait
+// for it and fail with database is locked.
+type rowLock struct {
+	db    *sql.DB
+	owner string
+	stop  chan struct{}
+	done  chan struct{}
+
+	// mu protects tx
+	mu sync.Mutex
+	// tx is the transaction of the running migration, if any
+	tx querier
+}
+
+// tryLock inserts the lock row, or takes it over once expired
+func (l *rowLock) tryLock(_ context.Context) (bool, string, error) {
+	_, err := l.db.Exec(`
+		CREATE TABLE IF NOT EXISTS migration_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			owner VARCHAR(255) NOT NULL,
+			acquired_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL
+		)`)
+	if err != nil {
+		return false, "", err
+	}
+
+	now := time.Now().UTC()
+
+	result, err := l.db.Exec(`
+		INSERT INTO migration_lock (id, owner, acquired_at, expires_at)
+		VALUES (1, ?1, ?2, ?3)
+		ON CONFLICT (id) DO UPDATE SET
+			owner = excluded.owner,
+			acquired_at = excluded.acquired_at,
+			expires_at = excluded.expires_at
+		WHERE migration_lock.expires_at <= excluded.acquired_at`,
+		l.owner, now, now.Add(lockLease))
+	if err != nil {
+		return false, "", err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, "", err
+	}
+
+	if affected > 0 {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.refresh()
+		return true, "", nil
+	}
+
+	var owner string
+	var acquiredAt time.Time
+	err = l.db.QueryRow("SELECT owner, acquired_at FROM migration_lock WHERE id = 1").Scan(&owner, &acquiredAt)
+	if err == sql.ErrNoRows {
+		return false, "unknown", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	return false, fmt.Sprintf("%s (since %s)", owner, acquiredAt.UTC().Format(time.RFC3339)), nil
+}
+
+// refresh extends the lock row until unlock
+func (l *rowLock) refresh() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(lockLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			// A transaction ending meanwhile is refreshed on the next tick
+			if err := l.extend(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				log.Error().Err(err).Msg("Failed to refresh migration lock")
+			}
+		}
+	}
+}
+
+// extend pushes the expiry of the lock row by lockLease
+func (l *rowLock) extend() error {
+	l.mu.Lock()
+	var q querier = l.db
+	if l.tx != nil {
+		q = l.tx
+	}
+	l.mu.Unlock()
+
+	_, err := q.Exec(
+		"UPDATE migration_lock SET expires_at = ? WHERE id = 1 AND owner = ?",
+		time.Now().UTC().Add(lockLease), l.owner,
+	)
+	return err
+}
+
+// through refreshes the lock row through the transaction of the running
+// migration, or through the database again once q is nil
+func (l *rowLock) through(q querier) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tx = q
+}
+
+// unlock stops refreshing and deletes the lock row
+func (l *rowLock) unlock() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+	}
+
+	_, err := l.db.Exec("DELETE FROM migration_lock WHERE id = 1 AND owner = ?", l.owner)
+	return err
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitLock(t *testing.T) {
+	t.Run("Refreshes through the running migration", func(t *testing.T) {
+		db := newEmptyDB(t)
+		m := NewManager(db, "sqlite")
+
+		unlock, err := m.lock()
+		require.NoError(t, err)
+		defer unlock()
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		// The transaction now holds the sqlite write lock
+		_, err = tx.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+		require.NoError(t, err)
+
+		l := m.held.(*rowLock)
+		assert.Error(t, l.extend())
+
+		l.through(tx)
+		assert.NoError(t, l.extend())
+
+		l.through(nil)
+		require.NoError(t, tx.Commit())
+		assert.NoError(t, l.extend())
+	})
+
+	t.Run("Refuses a held lock until released", func(t *testing.T) {
+		db := newEmptyDB(t)
+		m := NewManager(db, "sqlite")
+		m.SetLockTimeout(0)
+
+		unlock, err := m.lock()
+		require.NoError(t, err)
+
+		other := NewManager(db, "sqlite")
+		other.SetLockTimeout(0)
+		_, err = other.lock()
+		assert.Error(t, err)
+
+		unlock()
+
+		unlock, err = m.lock()
+		assert.NoError(t, err)
+		unlock()
+	})
+}
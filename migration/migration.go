@@ -14,30 +14,96 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// Executor runs the statements of a migration
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	// Driver returns the database driver, sqlite or postgres
+	Driver() string
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// executor binds a database or transaction to its driver
+type executor struct {
+	querier
+	driver string
+}
+
+// Driver returns the database driver
+func (e *executor) Driver() string {
+	return e.driver
+}
+
 // Migration represents a database migration
 type Migration struct {
 	Version     string
 	Description string
-	Up          func(*sql.DB) error
-	Down        func(*sql.DB) error
+	Up          func(Executor) error
+	Down        func(Executor) error
+	// NoTransaction runs the migration outside of a transaction, for
+	// statements postgres refuses to run in one like CREATE INDEX
+	// CONCURRENTLY. A failure may then leave it partially applied, so such a
+	// migration should hold a single statement.
+	NoTransaction bool
+	// Checksum identifies the content of the migration, so edits after it
+	// was applied are detected. It hashes the scripts of SQL migrations and
+	// the source of the functions of the migrations returned by GetAll.
+	// Migrations registered without one are not checked.
+	Checksum string
 }
 
+// defaultLockTimeout is how long Up and Down wait for the migration lock
+// unless configured otherwise
+const defaultLockTimeout = 5 * time.Minute
+
 // Manager handles database migrations
 type Manager struct {
-	db         *sql.DB
-	driver     string
-	migrations []Migration
+	db          *sql.DB
+	driver      string
+	migrations  []Migration
+	lockTimeout time.Duration
+	allowDrift  bool
+	// held is the migration lock while it is taken
+	held locker
 }
 
 // NewManager creates a new migration manager
 func NewManager(db *sql.DB, driver string) *Manager {
 	return &Manager{
-		db:         db,
-		driver:     driver,
-		migrations: []Migration{},
+		db:          db,
+		driver:      driver,
+		migrations:  []Migration{},
+		lockTimeout: defaultLockTimeout,
 	}
 }
 
+// SetLockTimeout sets how long Up and Down wait for another process holding
+// the migration lock. A zero timeout fails right away if the lock is held.
+func (m *Manager) SetLockTimeout(timeout time.Duration) {
+	m.lockTimeout = timeout
+}
+
+// driverName returns the driver passed to migrations, sqlite or postgres
+func (m *Manager) driverName() string {
+	if m.driver == "postgresql" {
+		return "postgres"
+	}
+	return m.driver
+}
+
+// SetAllowDrift lets Up run although applied migrations were modified or are
+// not registered anymore, or pending migrations are older than applied ones.
+func (m *Manager) SetAllowDrift(allow bool) {
+	m.allowDrift = allow
+}
+
 // Register adds a migration to the manager
 func (m *Manager) Register(migration Migration) {
 	m.migrations = append(m.migrations, migration)
@@ -54,6 +120,7 @@ func (m *Manager) createMigrationsTable() error {
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			version VARCHAR(255) NOT NULL UNIQUE,
 			description TEXT,
+			checksum VARCHAR(64),
 			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`
 	case "postgres", "postgresql":
@@ -62,6 +129,7 @@ func (m *Manager) createMigrationsTable() error {
 			id SERIAL PRIMARY KEY,
 			version VARCHAR(255) NOT NULL UNIQUE,
 			description TEXT,
+			checksum VARCHAR(64),
 			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
 		CREATE INDEX IF NOT EXISTS idx_version ON migrations(version)`
@@ -74,37 +142,26 @@ func (m *Manager) createMigrationsTable() error {
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	return nil
-}
-
-// isApplied checks if a migration version has been applied
-func (m *Manager) isApplied(version string) (bool, error) {
-	var count int
-	var query string
-	if m.driver == "postgres" || m.driver == "postgresql" {
-		query = "SELECT COUNT(*) FROM migrations WHERE version = $1"
-	} else {
-		query = "SELECT COUNT(*) FROM migrations WHERE version = ?"
-	}
-	err := m.db.QueryRow(query, version).Scan(&count)
-	if err != nil {
-		return false, fmt.Errorf("failed to check migration status: %w", err)
+	if err := m.addChecksumColumn(); err != nil {
+		return fmt.Errorf("failed to add checksum column to migrations table: %w", err)
 	}
-	return count > 0, nil
+
+	return nil
 }
 
 // recordMigration records a migration as applied
-func (m *Manager) recordMigration(version, description string) error {
+func (m *Manager) recordMigration(db Executor, migration *Migration) error {
 	var query string
 	if m.driver == "postgres" || m.driver == "postgresql" {
-		query = "INSERT INTO migrations (version, description, applied_at) VALUES ($1, $2, $3)"
+		query = "INSERT INTO migrations (version, description, checksum, applied_at) VALUES ($1, $2, $3, $4)"
 	} else {
-		query = "INSERT INTO migrations (version, description, applied_at) VALUES (?, ?, ?)"
+		query = "INSERT INTO migrations (version, description, checksum, applied_at) VALUES (?, ?, ?, ?)"
 	}
-	_, err := m.db.Exec(
+	_, err := db.Exec(
 		query,
-		version,
-		description,
+		migration.Version,
+		migration.Description,
+		sql.NullString{String: migration.Checksum, Valid: migration.Checksum != ""},
 		time.Now().UTC(),
 	)
 	if err != nil {
@@ -114,73 +171,120 @@ func (m *Manager) recordMigration(version, description string) error {
 }
 
 // removeMigration removes a migration record
-func (m *Manager) removeMigration(version string) error {
+func (m *Manager) removeMigration(db Executor, version string) error {
 	var query string
 	if m.driver == "postgres" || m.driver == "postgresql" {
 		query = "DELETE FROM migrations WHERE version = $1"
 	} else {
 		query = "DELETE FROM migrations WHERE version = ?"
 	}
-	_, err := m.db.Exec(query, version)
+	_, err := db.Exec(query, version)
 	if err != nil {
 		return fmt.Errorf("failed to remove migration record: %w", err)
 	}
 	return nil
 }
 
-// Up runs all pending migrations
+// run calls fn of a migration then record, which tracks the change in the
+// migrations table. Both run in one transaction unless the migration opts out,
+// so a failed migration leaves neither its changes nor its record behind.
+func (m *Manager) run(migration *Migration, fn, record func(Executor) error) error {
+	driver := m.driverName()
+
+	if migration.NoTransaction {
+		db := &executor{querier: m.db, driver: driver}
+		if err := fn(db); err != nil {
+			return err
+		}
+		return record(db)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	if m.held != nil {
+		m.held.through(tx)
+		defer m.held.through(nil)
+	}
+
+	db := &executor{querier: tx, driver: driver}
+	if err := fn(db); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := record(db); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Up runs all pending migrations. It holds the migration lock meanwhile, so
+// processes starting together apply every migration once. It refuses to run
+// on drift between the applied and registered migrations unless allowed.
 func (m *Manager) Up() error {
+	return m.UpTo("")
+}
+
+// UpTo runs the pending migrations up to and including the target version,
+// or all of them if the target is empty, like Up
+func (m *Manager) UpTo(target string) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if err := m.createMigrationsTable(); err != nil {
 		return err
 	}
 
-	sort.Slice(m.migrations, func(i, j int) bool {
-		return m.migrations[i].Version < m.migrations[j].Version
-	})
+	m.sort()
+
+	if target != "" && m.find(target) == nil {
+		return fmt.Errorf("migration %s not found in registered migrations", target)
+	}
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	if err := m.checkDrift(applied); err != nil {
+		return err
+	}
+
+	if err := m.backfillChecksums(applied); err != nil {
+		return err
+	}
 
 	appliedCount := 0
 
-	for _, migration := range m.migrations {
-		applied, err := m.isApplied(migration.Version)
-		if err != nil {
-			return err
+	for i := range m.migrations {
+		migration := &m.migrations[i]
+
+		if target != "" && migration.Version > target {
+			break
 		}
 
-		if applied {
+		if _, ok := applied[migration.Version]; ok {
 			log.Debug().
 				Str("version", migration.Version).
 				Msg("Migration already applied, skipping")
 			continue
 		}
 
-		log.Info().
-			Str("version", migration.Version).
-			Str("description", migration.Description).
-			Msg("Running migration")
-
-		tx, err := m.db.Begin()
-		if err != nil {
-			return fmt.Errorf("failed to start transaction: %w", err)
-		}
-
-		if err := migration.Up(m.db); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("migration %s failed: %w", migration.Version, err)
-		}
-
-		if err := m.recordMigration(migration.Version, migration.Description); err != nil {
-			tx.Rollback()
+		if err := m.apply(migration); err != nil {
 			return err
 		}
 
-		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("failed to commit transaction: %w", err)
-		}
-
-		log.Info().
-			Str("version", migration.Version).
-			Msg("Migration applied successfully")
-
 		appliedCount++
 	}
 
@@ -195,104 +299,396 @@ func (m *Manager) Up() error {
 	return nil
 }
 
-// Down rolls back the last migration
+// Down rolls back the last migration while holding the migration lock
 func (m *Manager) Down() error {
+	return m.DownSteps(1)
+}
+
+// DownSteps rolls back the last n applied migrations, latest first, like Down
+func (m *Manager) DownSteps(n int) error {
+	if n < 1 {
+		return fmt.Errorf("number of migrations to roll back must be positive, got %d", n)
+	}
+
+	return m.rollBackHistory(func(history []appliedMigration) ([]appliedMigration, error) {
+		if n > len(history) {
+			n = len(history)
+		}
+		return history[:n], nil
+	})
+}
+
+// DownTo rolls back the migrations applied after the target version, latest
+// first, like Down. The target version stays applied.
+func (m *Manager) DownTo(target string) error {
+	return m.rollBackHistory(func(history []appliedMigration) ([]appliedMigration, error) {
+		for i, record := range history {
+			if record.version == target {
+				return history[:i], nil
+			}
+		}
+		return nil, fmt.Errorf("migration %s is not applied", target)
+	})
+}
+
+// Redo rolls back the last migration and applies it again while holding the
+// migration lock, which picks up edits of the migration during development
+func (m *Manager) Redo() error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if err := m.createMigrationsTable(); err != nil {
 		return err
 	}
 
-	var version, description string
-	err := m.db.QueryRow(`
-		SELECT version, description
-		FROM migrations
-		ORDER BY applied_at DESC, id DESC
-		LIMIT 1
-	`).Scan(&version, &description)
+	history, err := m.history()
+	if err != nil {
+		return err
+	}
 
-	if err == sql.ErrNoRows {
-		log.Info().Msg("No migrations to roll back")
+	if len(history) == 0 {
+		log.Info().Msg("No migrations to redo")
 		return nil
 	}
+
+	migration, err := m.rollbackable(history[0])
+	if err != nil {
+		return err
+	}
+
+	// The migration is applied again as registered, so refuse to start on
+	// drift like up does
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	if err := m.checkDrift(applied); err != nil {
+		return err
+	}
+
+	if err := m.rollBack(migration, history[0]); err != nil {
+		return err
+	}
+
+	if err := m.apply(migration); err != nil {
+		return fmt.Errorf("migration %s was rolled back but applying it again failed, it is now pending: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// Baseline marks the registered migrations up to and including the target
+// version as applied without running them, to adopt a database whose schema
+// was created otherwise. Later migrations are left pending.
+func (m *Manager) Baseline(target string) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := m.createMigrationsTable(); err != nil {
+		return err
+	}
+
+	m.sort()
+
+	if m.find(target) == nil {
+		return fmt.Errorf("migration %s not found in registered migrations", target)
+	}
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	// Every migration is marked in one transaction so a failure leaves none
+	// of them marked
+	tx, err := m.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to get last migration: %w", err)
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	if m.held != nil {
+		m.held.through(tx)
+		defer m.held.through(nil)
 	}
 
-	var migration *Migration
+	db := &executor{querier: tx, driver: m.driverName()}
+	var marked []*Migration
+
 	for i := range m.migrations {
-		if m.migrations[i].Version == version {
-			migration = &m.migrations[i]
+		migration := &m.migrations[i]
+
+		if migration.Version > target {
 			break
 		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := m.recordMigration(db, migration); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		marked = append(marked, migration)
 	}
 
-	if migration == nil {
-		return fmt.Errorf("migration %s not found in registered migrations", version)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	if migration.Down == nil {
-		return fmt.Errorf("migration %s has no down function", version)
+
+	for _, migration := range marked {
+		log.Info().
+			Str("version", migration.Version).
+			Str("description", migration.Description).
+			Msg("Migration marked as applied")
 	}
 
 	log.Info().
-		Str("version", version).
-		Str("description", description).
-		Msg("Rolling back migration")
+		Str("version", target).
+		Int("count", len(marked)).
+		Msg("Database baselined")
 
-	tx, err := m.db.Begin()
+	return nil
+}
+
+// checkDrift fails on drift between the applied and registered migrations
+// unless drift is allowed, in which case it is logged
+func (m *Manager) checkDrift(applied map[string]appliedMigration) error {
+	drifts := m.drift(applied)
+	if len(drifts) > 0 && !m.allowDrift {
+		return driftError(drifts)
+	}
+	for _, d := range drifts {
+		log.Warn().
+			Str("version", d.version).
+			Str("drift", d.kind).
+			Msg("Migration drift allowed")
+	}
+	return nil
+}
+
+// sort orders the registered migrations by version
+func (m *Manager) sort() {
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+}
+
+// find returns the registered migration with the version, nil if none
+func (m *Manager) find(version string) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// history returns the applied migrations, the latest first
+func (m *Manager) history() ([]appliedMigration, error) {
+	rows, err := m.db.Query(`
+		SELECT version, COALESCE(description, ''), checksum
+		FROM migrations
+		ORDER BY applied_at DESC, id DESC
+	`)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
 	}
+	defer rows.Close()
 
-	if err := migration.Down(m.db); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("rollback of migration %s failed: %w", version, err)
+	var history []appliedMigration
+	for rows.Next() {
+		var record appliedMigration
+		var checksum sql.NullString
+
+		if err := rows.Scan(&record.version, &record.description, &checksum); err != nil {
+			return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+		}
+		record.checksum = checksum.String
+		history = append(history, record)
 	}
 
-	if err := m.removeMigration(version); err != nil {
-		tx.Rollback()
+	return history, rows.Err()
+}
+
+// apply runs a pending migration and records it as applied
+func (m *Manager) apply(migration *Migration) error {
+	log.Info().
+		Str("version", migration.Version).
+		Str("description", migration.Description).
+		Msg("Running migration")
+
+	err := m.run(migration, func(db Executor) error {
+		if err := migration.Up(db); err != nil {
+			return fmt.Errorf("migration %s failed: %w", migration.Version, err)
+		}
+		return nil
+	}, func(db Executor) error {
+		return m.recordMigration(db, migration)
+	})
+	if err != nil {
 		return err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	log.Info().
+		Str("version", migration.Version).
+		Msg("Migration applied successfully")
+
+	return nil
+}
+
+// rollbackable returns the registered migration of an applied one, or an
+// error if it can not be rolled back
+func (m *Manager) rollbackable(record appliedMigration) (*Migration, error) {
+	migration := m.find(record.version)
+
+	if migration == nil {
+		return nil, fmt.Errorf("migration %s not found in registered migrations", record.version)
+	}
+	if migration.Down == nil {
+		return nil, fmt.Errorf("migration %s has no down function", record.version)
+	}
+	return migration, nil
+}
+
+// rollBack rolls back an applied migration and removes its record
+func (m *Manager) rollBack(migration *Migration, record appliedMigration) error {
+	log.Info().
+		Str("version", record.version).
+		Str("description", record.description).
+		Msg("Rolling back migration")
+
+	err := m.run(migration, func(db Executor) error {
+		if err := migration.Down(db); err != nil {
+			return fmt.Errorf("rollback of migration %s failed: %w", record.version, err)
+		}
+		return nil
+	}, func(db Executor) error {
+		return m.removeMigration(db, record.version)
+	})
+	if err != nil {
+		return err
 	}
 
 	log.Info().
-		Str("version", version).
+		Str("version", record.version).
 		Msg("Migration rolled back successfully")
 
 	return nil
 }
 
-// Status shows the status of all migrations
-func (m *Manager) Status() error {
+// rollBackHistory rolls back the applied migrations picked from the history,
+// latest first, while holding the migration lock. It checks all of them can
+// be rolled back before starting.
+func (m *Manager) rollBackHistory(pick func(history []appliedMigration) ([]appliedMigration, error)) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	if err := m.createMigrationsTable(); err != nil {
 		return err
 	}
 
-	sort.Slice(m.migrations, func(i, j int) bool {
-		return m.migrations[i].Version < m.migrations[j].Version
-	})
+	history, err := m.history()
+	if err != nil {
+		return err
+	}
 
-	log.Info().Msg("Migration Status:")
-	log.Info().Msg("=================")
+	records, err := pick(history)
+	if err != nil {
+		return err
+	}
 
-	for _, migration := range m.migrations {
-		applied, err := m.isApplied(migration.Version)
+	if len(records) == 0 {
+		log.Info().Msg("No migrations to roll back")
+		return nil
+	}
+
+	migrations := make([]*Migration, 0, len(records))
+	for _, record := range records {
+		migration, err := m.rollbackable(record)
 		if err != nil {
 			return err
 		}
+		migrations = append(migrations, migration)
+	}
+
+	for i, record := range records {
+		if err := m.rollBack(migrations[i], record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Status shows the status of all migrations, including the applied ones
+// which are not registered anymore, and reports drift
+func (m *Manager) Status() error {
+	if err := m.createMigrationsTable(); err != nil {
+		return err
+	}
+
+	m.sort()
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return err
+	}
 
+	drifts := m.drift(applied)
+	drifted := map[string]string{}
+	for _, d := range drifts {
+		drifted[d.version] = d.kind
+	}
+
+	descriptions := map[string]string{}
+	for _, migration := range applied {
+		descriptions[migration.version] = migration.description
+	}
+	for _, migration := range m.migrations {
+		descriptions[migration.Version] = migration.Description
+	}
+
+	versions := make([]string, 0, len(descriptions))
+	for version := range descriptions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	log.Info().Msg("Migration Status:")
+	log.Info().Msg("=================")
+
+	for _, version := range versions {
 		status := "Pending"
-		if applied {
+		if _, ok := applied[version]; ok {
 			status = "Applied"
 		}
 
-		log.Info().
-			Str("version", migration.Version).
-			Str("description", migration.Description).
+		event := log.Info()
+		if kind, ok := drifted[version]; ok {
+			event = log.Warn().Str("drift", kind)
+		}
+
+		event.
+			Str("version", version).
+			Str("description", descriptions[version]).
 			Str("status", status).
 			Msg("")
 	}
 
+	if len(drifts) > 0 {
+		log.Warn().
+			Int("count", len(drifts)).
+			Msg("Migration drift detected, migrate up refuses to run unless --allow-drift is given")
+	}
+
 	return nil
 }
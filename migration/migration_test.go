@@ -0,0 +1,203 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitUp(t *testing.T) {
+	t.Run("Applies pending migrations once", func(t *testing.T) {
+		db := newEmptyDB(t)
+		var calls []string
+
+		m := NewManager(db, "sqlite")
+		m.Register(tableMigration("002", &calls))
+		m.Register(tableMigration("001", &calls))
+
+		require.NoError(t, m.Up())
+		require.NoError(t, m.Up())
+
+		assert.Equal(t, []string{"up 001", "up 002"}, calls)
+		assert.Equal(t, []string{"001", "002"}, appliedVersions(t, db))
+	})
+
+	t.Run("Rolls back a failed migration with its record", func(t *testing.T) {
+		db := newEmptyDB(t)
+		var calls []string
+
+		m := NewManager(db, "sqlite")
+		m.Register(tableMigration("001", &calls))
+		m.Register(Migration{
+			Version: "002",
+			Up: func(db Executor) error {
+				if _, err := db.Exec("CREATE TABLE t002 (id INTEGER PRIMARY KEY)"); err != nil {
+					return err
+				}
+				return errors.New("failed")
+			},
+		})
+
+		assert.Error(t, m.Up())
+		assert.True(t, hasTable(t, db, "t001"))
+		assert.False(t, hasTable(t, db, "t002"))
+		assert.Equal(t, []string{"001"}, appliedVersions(t, db))
+	})
+
+	t.Run("Keeps the statements of a failed migration without transaction", func(t *testing.T) {
+		db := newEmptyDB(t)
+
+		m := NewManager(db, "sqlite")
+		m.Register(Migration{
+			Version:       "001",
+			NoTransaction: true,
+			Up: func(db Executor) error {
+				if _, err := db.Exec("CREATE TABLE t001 (id INTEGER PRIMARY KEY)"); err != nil {
+					return err
+				}
+				return errors.New("failed")
+			},
+		})
+
+		assert.Error(t, m.Up())
+		assert.True(t, hasTable(t, db, "t001"))
+		assert.Empty(t, appliedVersions(t, db))
+	})
+}
+
+func TestUnitTargets(t *testing.T) {
+	// newManager registers migrations 001 to 003 with a fresh database
+	newManager := func(t *testing.T, calls *[]string) *Manager {
+		t.Helper()
+
+		m := NewManager(newEmptyDB(t), "sqlite")
+		for _, version := range []string{"001", "002", "003"} {
+			m.Register(tableMigration(version, calls))
+		}
+		return m
+	}
+
+	t.Run("Applies up to the target version", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+
+		require.NoError(t, m.UpTo("002"))
+		assert.Equal(t, []string{"up 001", "up 002"}, calls)
+
+		assert.Error(t, m.UpTo("004"))
+
+		require.NoError(t, m.Up())
+		assert.Equal(t, []string{"up 001", "up 002", "up 003"}, calls)
+	})
+
+	t.Run("Rolls back the latest migrations first", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+		require.NoError(t, m.Up())
+
+		calls = nil
+		require.NoError(t, m.DownSteps(2))
+		assert.Equal(t, []string{"down 003", "down 002"}, calls)
+		assert.Equal(t, []string{"001"}, appliedVersions(t, m.db))
+		assert.False(t, hasTable(t, m.db, "t002"))
+
+		assert.Error(t, m.DownSteps(0))
+	})
+
+	t.Run("Rolls back down to the target version", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+		require.NoError(t, m.Up())
+
+		calls = nil
+		require.NoError(t, m.DownTo("001"))
+		assert.Equal(t, []string{"down 003", "down 002"}, calls)
+		assert.Equal(t, []string{"001"}, appliedVersions(t, m.db))
+
+		assert.Error(t, m.DownTo("003"))
+	})
+
+	t.Run("Rolls back nothing if a migration has no down function", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+		m.migrations[1].Down = nil
+		require.NoError(t, m.Up())
+
+		calls = nil
+		assert.Error(t, m.DownTo("001"))
+		assert.Empty(t, calls)
+		assert.Equal(t, []string{"001", "002", "003"}, appliedVersions(t, m.db))
+	})
+
+	t.Run("Redoes the latest migration", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+		require.NoError(t, m.Up())
+
+		calls = nil
+		require.NoError(t, m.Redo())
+		assert.Equal(t, []string{"down 003", "up 003"}, calls)
+		assert.Equal(t, []string{"001", "002", "003"}, appliedVersions(t, m.db))
+		assert.True(t, hasTable(t, m.db, "t003"))
+	})
+
+	t.Run("Refuses to redo on drift", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+		for i := range m.migrations {
+			m.migrations[i].Checksum = "applied"
+		}
+		require.NoError(t, m.Up())
+
+		calls = nil
+		m.migrations[0].Checksum = "edited"
+		err := m.Redo()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "001 is modified")
+		assert.Empty(t, calls)
+	})
+
+	t.Run("Reports a redo left rolled back", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+		require.NoError(t, m.Up())
+
+		m.migrations[2].Up = func(Executor) error { return errors.New("boom") }
+		err := m.Redo()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "migration 003 was rolled back")
+		assert.Equal(t, []string{"001", "002"}, appliedVersions(t, m.db))
+	})
+
+	t.Run("Baselines without running migrations", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+
+		require.NoError(t, m.Baseline("002"))
+		assert.Empty(t, calls)
+		assert.Equal(t, []string{"001", "002"}, appliedVersions(t, m.db))
+
+		require.NoError(t, m.Up())
+		assert.Equal(t, []string{"up 003"}, calls)
+
+		assert.Error(t, m.Baseline("004"))
+	})
+
+	t.Run("Baselines nothing if a record fails", func(t *testing.T) {
+		var calls []string
+		m := newManager(t, &calls)
+		require.NoError(t, m.createMigrationsTable())
+		_, err := m.db.Exec(`CREATE TRIGGER refuse_002 BEFORE INSERT ON migrations
+			WHEN NEW.version = '002' BEGIN SELECT RAISE(ABORT, 'refused'); END`)
+		require.NoError(t, err)
+
+		assert.Error(t, m.Baseline("003"))
+		assert.Empty(t, appliedVersions(t, m.db))
+	})
+}
@@ -5,48 +5,98 @@
 package migration
 
 import (
-	"database/sql"
 	"fmt"
-	"strings"
 )
 
-// detectDriver attempts to determine the database driver type
-func detectDriver(db *sql.DB) string {
-	// Check SQLite
-	_, err := db.Exec("SELECT sqlite_version()")
-	if err == nil {
-		return "sqlite"
-	}
-
-	// Check PostgreSQL
-	_, err = db.Exec("SELECT version()")
-	if err == nil {
-		var version string
-		db.QueryRow("SELECT version()").Scan(&version)
-		if strings.Contains(strings.ToLower(version), "postgresql") {
-			return "postgres"
-		}
-	}
-
-	// Unknown database driver
-	return "unknown"
-}
-
-// GetAll returns all registered migrations
+// GetAll returns the migrations written in Go, checksummed from the source
+// of their functions. SQL migrations are loaded from Files.
 func GetAll() []Migration {
-	return []Migration{
+	migrations := []Migration{
 		{
 			Version:     "20250101000003",
 			Description: "Create options table",
 			Up:          createOptionsTable,
 			Down:        dropOptionsTable,
 		},
+		{
+			Version:     "20250101000004",
+			Description: "Add version column to options table",
+			Up:          addOptionsVersionColumn,
+			Down:        dropOptionsVersionColumn,
+		},
+		{
+			Version:     "20250101000005",
+			Description: "Create option revisions table",
+			Up:          createOptionRevisionsTable,
+			Down:        dropOptionRevisionsTable,
+		},
+		{
+			Version:     "20250101000006",
+			Description: "Add expires_at column to options table",
+			Up:          addOptionsExpiresAtColumn,
+			Down:        dropOptionsExpiresAtColumn,
+		},
+		{
+			Version:     "20250101000007",
+			Description: "Add namespace to options and option revisions",
+			Up:          addOptionsNamespace,
+			Down:        dropOptionsNamespace,
+		},
+		{
+			Version:     "20250101000008",
+			Description: "Store option values as JSON documents",
+			Up:          convertOptionValuesToJSON,
+			Down:        convertOptionValuesToText,
+		},
+		{
+			Version:     "20250101000009",
+			Description: "Create option schemas table",
+			Up:          createOptionSchemasTable,
+			Down:        dropOptionSchemasTable,
+		},
+		{
+			Version:     "20250101000010",
+			Description: "Add deleted_at column to options table",
+			Up:          addOptionsDeletedAtColumn,
+			Down:        dropOptionsDeletedAtColumn,
+		},
+		{
+			Version:     "20250101000011",
+			Description: "Create audit events table",
+			Up:          createAuditEventsTable,
+			Down:        dropAuditEventsTable,
+		},
+		{
+			Version:     "20250101000012",
+			Description: "Add encryption columns to options and option revisions",
+			Up:          addOptionsEncryptionColumns,
+			Down:        dropOptionsEncryptionColumns,
+		},
+		{
+			Version:     "20250101000013",
+			Description: "Create leases table",
+			Up:          createLeasesTable,
+			Down:        dropLeasesTable,
+		},
+		{
+			Version:     "20250101000014",
+			Description: "Add transaction id to option revisions",
+			Up:          addOptionRevisionsTxid,
+			Down:        dropOptionRevisionsTxid,
+		},
+	}
+
+	// The functions of every migration above are declared in this file
+	if err := goChecksums(migrations, registrySource); err != nil {
+		panic(err.Error())
 	}
+
+	return migrations
 }
 
 // createOptionsTable creates the options table
-func createOptionsTable(db *sql.DB) error {
-	driver := detectDriver(db)
+func createOptionsTable(db Executor) error {
+	driver := db.Driver()
 	var query string
 
 	switch driver {
@@ -78,7 +128,482 @@ func createOptionsTable(db *sql.DB) error {
 }
 
 // dropOptionsTable drops the options table
-func dropOptionsTable(db *sql.DB) error {
+func dropOptionsTable(db Executor) error {
 	_, err := db.Exec("DROP TABLE IF EXISTS options")
 	return err
 }
+
+// addOptionsVersionColumn adds the optimistic concurrency version column
+func addOptionsVersionColumn(db Executor) error {
+	_, err := db.Exec("ALTER TABLE options ADD COLUMN version INTEGER NOT NULL DEFAULT 1")
+	return err
+}
+
+// dropOptionsVersionColumn drops the version column from the options table
+func dropOptionsVersionColumn(db Executor) error {
+	_, err := db.Exec("ALTER TABLE options DROP COLUMN version")
+	return err
+}
+
+// createOptionRevisionsTable creates the option revisions table and seeds it
+// with the current value of every option
+func createOptionRevisionsTable(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		CREATE TABLE option_revisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			option_key VARCHAR(255) NOT NULL,
+			value TEXT,
+			version INTEGER NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX idx_option_revisions_key ON option_revisions(option_key, id)`
+	case "postgres":
+		query = `
+		CREATE TABLE option_revisions (
+			id BIGSERIAL PRIMARY KEY,
+			option_key VARCHAR(255) NOT NULL,
+			value TEXT,
+			version INTEGER NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX idx_option_revisions_key ON option_revisions(option_key, id)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO option_revisions (option_key, value, version, action, created_at)
+		SELECT key, value, version, 'create', updated_at FROM options ORDER BY id`)
+	return err
+}
+
+// dropOptionRevisionsTable drops the option revisions table
+func dropOptionRevisionsTable(db Executor) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS option_revisions")
+	return err
+}
+
+// addOptionsExpiresAtColumn adds the optional expiry column to the options table
+func addOptionsExpiresAtColumn(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		ALTER TABLE options ADD COLUMN expires_at DATETIME;
+		CREATE INDEX idx_options_expires_at ON options(expires_at)`
+	case "postgres":
+		query = `
+		ALTER TABLE options ADD COLUMN expires_at TIMESTAMP;
+		CREATE INDEX idx_options_expires_at ON options(expires_at)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// dropOptionsExpiresAtColumn drops the expiry column from the options table
+func dropOptionsExpiresAtColumn(db Executor) error {
+	_, err := db.Exec(`
+		DROP INDEX IF EXISTS idx_options_expires_at;
+		ALTER TABLE options DROP COLUMN expires_at`)
+	return err
+}
+
+// addOptionsNamespace scopes option keys by namespace. Existing rows are moved
+// to the default namespace.
+func addOptionsNamespace(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		// SQLite cannot alter a UNIQUE constraint so the table is rebuilt
+		query = `
+		CREATE TABLE options_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			namespace VARCHAR(255) NOT NULL DEFAULT 'default',
+			key VARCHAR(255) NOT NULL,
+			value TEXT,
+			version INTEGER NOT NULL DEFAULT 1,
+			expires_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (namespace, key)
+		);
+		INSERT INTO options_new (id, namespace, key, value, version, expires_at, created_at, updated_at)
+			SELECT id, 'default', key, value, version, expires_at, created_at, updated_at FROM options;
+		DROP TABLE options;
+		ALTER TABLE options_new RENAME TO options;
+		CREATE INDEX idx_options_expires_at ON options(expires_at);
+		ALTER TABLE option_revisions ADD COLUMN namespace VARCHAR(255) NOT NULL DEFAULT 'default';
+		DROP INDEX IF EXISTS idx_option_revisions_key;
+		CREATE INDEX idx_option_revisions_key ON option_revisions(namespace, option_key, id)`
+	case "postgres":
+		query = `
+		ALTER TABLE options ADD COLUMN namespace VARCHAR(255) NOT NULL DEFAULT 'default';
+		ALTER TABLE options DROP CONSTRAINT IF EXISTS options_key_key;
+		ALTER TABLE options ADD CONSTRAINT options_namespace_key_key UNIQUE (namespace, key);
+		ALTER TABLE option_revisions ADD COLUMN namespace VARCHAR(255) NOT NULL DEFAULT 'default';
+		DROP INDEX IF EXISTS idx_option_revisions_key;
+		CREATE INDEX idx_option_revisions_key ON option_revisions(namespace, option_key, id)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// dropOptionsNamespace restores the global key uniqueness. Options and
+// revisions outside the default namespace are discarded.
+func dropOptionsNamespace(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		CREATE TABLE options_old (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key VARCHAR(255) NOT NULL UNIQUE,
+			value TEXT,
+			version INTEGER NOT NULL DEFAULT 1,
+			expires_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO options_old (id, key, value, version, expires_at, created_at, updated_at)
+			SELECT id, key, value, version, expires_at, created_at, updated_at FROM options WHERE namespace = 'default';
+		DROP TABLE options;
+		ALTER TABLE options_old RENAME TO options;
+		CREATE INDEX idx_options_expires_at ON options(expires_at);
+		DELETE FROM option_revisions WHERE namespace <> 'default';
+		DROP INDEX IF EXISTS idx_option_revisions_key;
+		ALTER TABLE option_revisions DROP COLUMN namespace;
+		CREATE INDEX idx_option_revisions_key ON option_revisions(option_key, id)`
+	case "postgres":
+		query = `
+		DELETE FROM options WHERE namespace <> 'default';
+		ALTER TABLE options DROP CONSTRAINT IF EXISTS options_namespace_key_key;
+		ALTER TABLE options DROP COLUMN namespace;
+		ALTER TABLE options ADD CONSTRAINT options_key_key UNIQUE (key);
+		DELETE FROM option_revisions WHERE namespace <> 'default';
+		DROP INDEX IF EXISTS idx_option_revisions_key;
+		ALTER TABLE option_revisions DROP COLUMN namespace;
+		CREATE INDEX idx_option_revisions_key ON option_revisions(option_key, id)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// convertOptionValuesToJSON encodes the existing plain text values as JSON strings
+func convertOptionValuesToJSON(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		UPDATE options SET value = json_quote(value) WHERE value IS NOT NULL;
+		UPDATE option_revisions SET value = json_quote(value) WHERE value IS NOT NULL`
+	case "postgres":
+		query = `
+		UPDATE options SET value = to_json(value)::text WHERE value IS NOT NULL;
+		UPDATE option_revisions SET value = to_json(value)::text WHERE value IS NOT NULL`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// convertOptionValuesToText decodes JSON string values back to plain text.
+// Other JSON documents are kept as is.
+func convertOptionValuesToText(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		UPDATE options SET value = json_extract(value, '$') WHERE json_valid(value) AND json_type(value) = 'text';
+		UPDATE option_revisions SET value = json_extract(value, '$') WHERE json_valid(value) AND json_type(value) = 'text'`
+	case "postgres":
+		query = `
+		UPDATE options SET value = value::json #>> '{}' WHERE json_typeof(value::json) = 'string';
+		UPDATE option_revisions SET value = value::json #>> '{}' WHERE json_typeof(value::json) = 'string'`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// createOptionSchemasTable creates the table holding JSON Schemas per key prefix
+func createOptionSchemasTable(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		CREATE TABLE option_schemas (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			namespace VARCHAR(255) NOT NULL DEFAULT 'default',
+			prefix VARCHAR(255) NOT NULL,
+			schema TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (namespace, prefix)
+		)`
+	case "postgres":
+		query = `
+		CREATE TABLE option_schemas (
+			id SERIAL PRIMARY KEY,
+			namespace VARCHAR(255) NOT NULL DEFAULT 'default',
+			prefix VARCHAR(255) NOT NULL,
+			schema TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (namespace, prefix)
+		)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// dropOptionSchemasTable drops the option schemas table
+func dropOptionSchemasTable(db Executor) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS option_schemas")
+	return err
+}
+
+// addOptionsDeletedAtColumn adds the soft delete column to the options table
+func addOptionsDeletedAtColumn(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		ALTER TABLE options ADD COLUMN deleted_at DATETIME;
+		CREATE INDEX idx_options_deleted_at ON options(deleted_at)`
+	case "postgres":
+		query = `
+		ALTER TABLE options ADD COLUMN deleted_at TIMESTAMP;
+		CREATE INDEX idx_options_deleted_at ON options(deleted_at)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// dropOptionsDeletedAtColumn drops the soft delete column from the options
+// table. Options in the trash are removed for good.
+func dropOptionsDeletedAtColumn(db Executor) error {
+	_, err := db.Exec(`
+		DELETE FROM options WHERE deleted_at IS NOT NULL;
+		DROP INDEX IF EXISTS idx_options_deleted_at;
+		ALTER TABLE options DROP COLUMN deleted_at`)
+	return err
+}
+
+// createAuditEventsTable creates the audit events table
+func createAuditEventsTable(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		CREATE TABLE audit_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			request_id VARCHAR(255) NOT NULL DEFAULT '',
+			actor VARCHAR(255) NOT NULL,
+			remote_ip VARCHAR(64) NOT NULL DEFAULT '',
+			method VARCHAR(10) NOT NULL,
+			path TEXT NOT NULL,
+			namespace VARCHAR(255) NOT NULL DEFAULT 'default',
+			option_key VARCHAR(255) NOT NULL DEFAULT '',
+			old_value_hash VARCHAR(64),
+			new_value_hash VARCHAR(64),
+			status INTEGER NOT NULL,
+			result VARCHAR(20) NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+		CREATE INDEX idx_audit_events_key ON audit_events(namespace, option_key, id)`
+	case "postgres":
+		query = `
+		CREATE TABLE audit_events (
+			id BIGSERIAL PRIMARY KEY,
+			request_id VARCHAR(255) NOT NULL DEFAULT '',
+			actor VARCHAR(255) NOT NULL,
+			remote_ip VARCHAR(64) NOT NULL DEFAULT '',
+			method VARCHAR(10) NOT NULL,
+			path TEXT NOT NULL,
+			namespace VARCHAR(255) NOT NULL DEFAULT 'default',
+			option_key VARCHAR(255) NOT NULL DEFAULT '',
+			old_value_hash VARCHAR(64),
+			new_value_hash VARCHAR(64),
+			status INTEGER NOT NULL,
+			result VARCHAR(20) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+		CREATE INDEX idx_audit_events_key ON audit_events(namespace, option_key, id)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// dropAuditEventsTable drops the audit events table
+func dropAuditEventsTable(db Executor) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS audit_events")
+	return err
+}
+
+// addOptionsEncryptionColumns adds the columns of encrypted secret values to
+// the options and option revisions tables
+func addOptionsEncryptionColumns(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite", "postgres":
+		query = `
+		ALTER TABLE options ADD COLUMN secret BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE options ADD COLUMN key_id VARCHAR(255);
+		ALTER TABLE options ADD COLUMN data_key TEXT;
+		ALTER TABLE option_revisions ADD COLUMN secret BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE option_revisions ADD COLUMN key_id VARCHAR(255);
+		ALTER TABLE option_revisions ADD COLUMN data_key TEXT`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// dropOptionsEncryptionColumns drops the encryption columns. It refuses to
+// run while encrypted values are stored since they could not be read anymore.
+func dropOptionsEncryptionColumns(db Executor) error {
+	var count int64
+	err := db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM options WHERE secret) +
+			(SELECT COUNT(*) FROM option_revisions WHERE secret)`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%d encrypted option values are stored, store them unencrypted before rolling back", count)
+	}
+
+	_, err = db.Exec(`
+		ALTER TABLE options DROP COLUMN secret;
+		ALTER TABLE options DROP COLUMN key_id;
+		ALTER TABLE options DROP COLUMN data_key;
+		ALTER TABLE option_revisions DROP COLUMN secret;
+		ALTER TABLE option_revisions DROP COLUMN key_id;
+		ALTER TABLE option_revisions DROP COLUMN data_key`)
+	return err
+}
+
+// createLeasesTable creates the table of the leases backing distributed locks.
+// Released and expired leases are kept so fencing tokens keep increasing.
+func createLeasesTable(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite":
+		query = `
+		CREATE TABLE leases (
+			name VARCHAR(255) PRIMARY KEY,
+			owner VARCHAR(255) NOT NULL,
+			token VARCHAR(64) NOT NULL,
+			fence INTEGER NOT NULL DEFAULT 1,
+			acquired_at DATETIME NOT NULL,
+			expires_at DATETIME NOT NULL
+		)`
+	case "postgres":
+		query = `
+		CREATE TABLE leases (
+			name VARCHAR(255) PRIMARY KEY,
+			owner VARCHAR(255) NOT NULL,
+			token VARCHAR(64) NOT NULL,
+			fence BIGINT NOT NULL DEFAULT 1,
+			acquired_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL
+		)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// dropLeasesTable drops the leases table
+func dropLeasesTable(db Executor) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS leases")
+	return err
+}
+
+// addOptionRevisionsTxid adds the id of the transaction recording each option
+// revision. On postgres revision ids are handed out before their transaction
+// commits, so changes are streamed in transaction order instead. Revisions
+// recorded before have transaction id zero.
+func addOptionRevisionsTxid(db Executor) error {
+	driver := db.Driver()
+	var query string
+
+	switch driver {
+	case "sqlite", "postgres":
+		query = `
+		ALTER TABLE option_revisions ADD COLUMN txid BIGINT NOT NULL DEFAULT 0;
+		CREATE INDEX idx_option_revisions_txid ON option_revisions (txid, id)`
+	default:
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+
+	_, err := db.Exec(query)
+	return err
+}
+
+// dropOptionRevisionsTxid drops the transaction id of option revisions
+func dropOptionRevisionsTxid(db Executor) error {
+	_, err := db.Exec(`
+		DROP INDEX IF EXISTS idx_option_revisions_txid;
+		ALTER TABLE option_revisions DROP COLUMN txid`)
+	return err
+}
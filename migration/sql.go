@@ -0,0 +1,239 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The directory is embedded whole so it may hold no SQL migration yet
+//
+//go:embed migrations
+var embedded embed.FS
+
+// Dir is the directory of the SQL migrations in the source tree, relative to
+// the repository root
+const Dir = "migration/migrations"
+
+// Drivers lists the database drivers a SQL migration has scripts for
+var Drivers = []string{"sqlite", "postgres"}
+
+// noTransactionDirective opts a SQL migration out of the transaction when it
+// is a line of its up or down script
+const noTransactionDirective = "-- zewi:no-transaction"
+
+// sqlFilePattern matches <version>_<name>.<driver>.<up|down>.sql
+var sqlFilePattern = regexp.MustCompile(`^(\d{14})_([a-z0-9_]+)\.([a-z]+)\.(up|down)\.sql$`)
+
+// sqlNamePattern matches the name of a SQL migration
+var sqlNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// sqlMigration collects the scripts of a SQL migration for every driver
+type sqlMigration struct {
+	name string
+	up   map[string]string
+	down map[string]string
+}
+
+// Files returns the SQL migrations shipped with the binary
+func Files() fs.FS {
+	files, err := fs.Sub(embedded, "migrations")
+	if err != nil {
+		panic(err)
+	}
+	return files
+}
+
+// isDriver reports whether SQL migrations support the driver
+func isDriver(driver string) bool {
+	for _, d := range Drivers {
+		if d == driver {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDirective reports whether a line of the script is the directive
+func hasDirective(script, directive string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		if strings.TrimSpace(line) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// describe turns a migration name like create_leases_table into
+// "Create leases table"
+func describe(name string) string {
+	description := strings.ReplaceAll(name, "_", " ")
+	return strings.ToUpper(description[:1]) + description[1:]
+}
+
+// script returns a function running the script of the migration for the
+// driver of the executor
+func script(version, direction string, scripts map[string]string) func(Executor) error {
+	return func(db Executor) error {
+		query, ok := scripts[db.Driver()]
+		if !ok {
+			return fmt.Errorf("migration %s has no %s script for driver %s", version, direction, db.Driver())
+		}
+
+		_, err := db.Exec(query)
+		return err
+	}
+}
+
+// LoadSQL reads the SQL migrations at the root of fsys for a driver. Files
+// are named <version>_<name>.<driver>.<up|down>.sql and every migration needs
+// an up script for the driver, the down script is optional.
+func LoadSQL(fsys fs.FS, driver string) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := map[string]*sqlMigration{}
+
+	for _, file := range files {
+		match := sqlFilePattern.FindStringSubmatch(file)
+		if match == nil {
+			return nil, fmt.Errorf("invalid SQL migration file name %s, expected <version>_<name>.<driver>.<up|down>.sql", file)
+		}
+		version, name, fileDriver, direction := match[1], match[2], match[3], match[4]
+
+		if !isDriver(fileDriver) {
+			return nil, fmt.Errorf("SQL migration %s is for unsupported driver %s", file, fileDriver)
+		}
+
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &sqlMigration{name: name, up: map[string]string{}, down: map[string]string{}}
+			migrations[version] = migration
+		}
+		if migration.name != name {
+			return nil, fmt.Errorf("SQL migration %s is named both %s and %s", version, migration.name, name)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		if direction == "up" {
+			migration.up[fileDriver] = string(data)
+		} else {
+			migration.down[fileDriver] = string(data)
+		}
+	}
+
+	versions := make([]string, 0, len(migrations))
+	for version := range migrations {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	result := make([]Migration, 0, len(versions))
+	for _, version := range versions {
+		m := migrations[version]
+
+		up, ok := m.up[driver]
+		if !ok {
+			return nil, fmt.Errorf("SQL migration %s_%s has no up script for driver %s", version, m.name, driver)
+		}
+
+		migration := Migration{
+			Version:       version,
+			Description:   describe(m.name),
+			Up:            script(version, "up", m.up),
+			NoTransaction: hasDirective(up, noTransactionDirective),
+		}
+
+		if down, ok := m.down[driver]; ok {
+			migration.Down = script(version, "down", m.down)
+			migration.NoTransaction = migration.NoTransaction || hasDirective(down, noTransactionDirective)
+		}
+
+		migration.Checksum = checksum(up, m.down[driver])
+
+		result = append(result, migration)
+	}
+
+	return result, nil
+}
+
+// Create scaffolds the up and down scripts of a new SQL migration for every
+// driver in dir and returns their paths. The version is the current UTC time.
+// The directory must exist, so a relative dir resolved from the wrong working
+// directory fails instead of scattering files.
+func Create(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(strings.TrimSpace(name)))
+	if !sqlNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q, use letters, digits and underscores", name)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("migrations directory %s does not exist, run from the repository root or pass its path with --dir", dir)
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+
+	var paths []string
+	for _, driver := range Drivers {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.%s.sql", version, name, driver, direction))
+
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+			if err != nil {
+				return paths, err
+			}
+
+			_, err = fmt.Fprintf(file, "-- %s: %s (%s, %s)\n", version, describe(name), driver, direction)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return paths, err
+			}
+
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// RegisterSQL registers the SQL migrations of fsys for the driver of the
+// manager. It fails if a version is already registered.
+func (m *Manager) RegisterSQL(fsys fs.FS) error {
+	migrations, err := LoadSQL(fsys, m.driverName())
+	if err != nil {
+		return err
+	}
+
+	registered := map[string]bool{}
+	for _, migration := range m.migrations {
+		registered[migration.Version] = true
+	}
+
+	for _, migration := range migrations {
+		if registered[migration.Version] {
+			return fmt.Errorf("migration %s is registered twice", migration.Version)
+		}
+		m.Register(migration)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitCreate(t *testing.T) {
+	t.Run("Scaffolds loadable migrations", func(t *testing.T) {
+		dir := t.TempDir()
+
+		paths, err := Create(dir, "Add some-column")
+		assert.NoError(t, err)
+		assert.Len(t, paths, 2*len(Drivers))
+
+		for _, driver := range Drivers {
+			migrations, err := LoadSQL(os.DirFS(dir), driver)
+			require.NoError(t, err)
+			require.Len(t, migrations, 1)
+			assert.Equal(t, "Add some column", migrations[0].Description)
+			assert.NotEmpty(t, migrations[0].Checksum)
+			assert.NotNil(t, migrations[0].Down)
+		}
+	})
+
+	t.Run("Fails if the directory is missing", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "missing")
+
+		paths, err := Create(dir, "add_some_column")
+		assert.Error(t, err)
+		assert.Empty(t, paths)
+
+		_, err = os.Stat(dir)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Rejects invalid names", func(t *testing.T) {
+		_, err := Create(t.TempDir(), "drop;table")
+		assert.Error(t, err)
+	})
+}
+
+func TestUnitFiles(t *testing.T) {
+	t.Run("Embedded migrations load for every driver", func(t *testing.T) {
+		for _, driver := range Drivers {
+			_, err := LoadSQL(Files(), driver)
+			assert.NoError(t, err)
+		}
+	})
+}
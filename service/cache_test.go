@@ -0,0 +1,78 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitCache(t *testing.T) {
+	t.Run("Gets cached values", func(t *testing.T) {
+		cache := NewCache(2)
+
+		_, ok := cache.Get("a")
+		assert.False(t, ok)
+
+		assert.False(t, cache.Set("a", 1, time.Minute))
+		assert.False(t, cache.Set("b", nil, time.Minute))
+
+		value, ok := cache.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+
+		value, ok = cache.Get("b")
+		assert.True(t, ok)
+		assert.Nil(t, value)
+	})
+
+	t.Run("Evicts the least recently used entry", func(t *testing.T) {
+		cache := NewCache(2)
+
+		cache.Set("a", 1, time.Minute)
+		cache.Set("b", 2, time.Minute)
+		cache.Get("a")
+
+		assert.True(t, cache.Set("c", 3, time.Minute))
+		assert.Equal(t, 2, cache.Len())
+
+		_, ok := cache.Get("b")
+		assert.False(t, ok)
+		_, ok = cache.Get("a")
+		assert.True(t, ok)
+
+		assert.False(t, cache.Set("a", 4, time.Minute))
+		value, _ := cache.Get("a")
+		assert.Equal(t, 4, value)
+	})
+
+	t.Run("Expires entries", func(t *testing.T) {
+		cache := NewCache(2)
+
+		cache.Set("a", 1, time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		_, ok := cache.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("Deletes and purges entries", func(t *testing.T) {
+		cache := NewCache(3)
+
+		cache.Set("a", 1, time.Minute)
+		cache.Set("b", 2, time.Minute)
+		cache.Delete("a")
+
+		_, ok := cache.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 1, cache.Len())
+
+		cache.Purge()
+		assert.Equal(t, 0, cache.Len())
+	})
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// EncodeCursor encodes the values identifying a position in a listing as an
+// opaque, URL safe cursor
+func EncodeCursor(values ...string) string {
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes a cursor created by EncodeCursor that must hold
+// exactly count values
+func DecodeCursor(cursor string, count int) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil || len(values) != count {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return values, nil
+}
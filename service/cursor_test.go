@@ -0,0 +1,30 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitCursor(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		cursor := EncodeCursor("updated_at", "2025-01-01T00:00:00Z", "app/name")
+		assert.NotContains(t, cursor, "/")
+		assert.NotContains(t, cursor, "=")
+
+		values, err := DecodeCursor(cursor, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"updated_at", "2025-01-01T00:00:00Z", "app/name"}, values)
+	})
+
+	t.Run("Rejects invalid cursors", func(t *testing.T) {
+		for _, cursor := range []string{"", "!!!", EncodeCursor("a"), "eyJhIjoxfQ"} {
+			_, err := DecodeCursor(cursor, 2)
+			assert.Error(t, err, cursor)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// FormatJSON is the JSON options document format
+	FormatJSON = "json"
+	// FormatYAML is the YAML options document format
+	FormatYAML = "yaml"
+)
+
+// optionsDocument is the exported form of the options of a namespace
+type optionsDocument struct {
+	Namespace string                     `json:"namespace"`
+	Options   map[string]json.RawMessage `json:"options"`
+	// Secrets lists the keys of the options that are secret
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+// IsValidFormat reports whether the options document format is supported
+func IsValidFormat(format string) bool {
+	return format == FormatJSON || format == FormatYAML
+}
+
+// FormatFromPath guesses the options document format from a file name,
+// defaulting to JSON
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// EncodeOptions encodes option values, which must be JSON documents, and the
+// keys of the secret options as an options document. Keys are written in
+// sorted order.
+func EncodeOptions(format, namespace string, values map[string]string, secrets []string) ([]byte, error) {
+	document := optionsDocument{
+		Namespace: namespace,
+		Options:   make(map[string]json.RawMessage, len(values)),
+		Secrets:   append([]string(nil), secrets...),
+	}
+	for key, value := range values {
+		document.Options[key] = json.RawMessage(value)
+	}
+	sort.Strings(document.Secrets)
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode options: %w", err)
+	}
+
+	switch format {
+	case FormatJSON:
+		return append(data, '\n'), nil
+	case FormatYAML:
+		// JSON is valid YAML, decoding it into nodes keeps numbers as written
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, fmt.Errorf("failed to encode options: %w", err)
+		}
+		resetStyle(&node)
+
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return nil, fmt.Errorf("failed to encode options: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// resetStyle switches YAML nodes decoded from JSON to the default block style
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// DecodeOptions decodes an options document into option values normalized as
+// JSON documents and the keys of the secret options. The namespace in the
+// document is ignored so options can be moved between namespaces.
+func DecodeOptions(format string, data []byte) (map[string]string, []string, error) {
+	values := make(map[string]string)
+	var secrets []string
+
+	switch format {
+	case FormatJSON:
+		var document optionsDocument
+		if err := json.Unmarshal(data, &document); err != nil {
+			return nil, nil, fmt.Errorf("invalid options document: %w", err)
+		}
+		if document.Options == nil {
+			return nil, nil, fmt.Errorf("invalid options document: missing options")
+		}
+
+		for key, raw := range document.Options {
+			value, err := NormalizeJSON(raw)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid value of option %s: %w", key, err)
+			}
+			values[key] = value
+		}
+		secrets = document.Secrets
+	case FormatYAML:
+		var document struct {
+			Options map[string]interface{} `yaml:"options"`
+			Secrets []string               `yaml:"secrets"`
+		}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, nil, fmt.Errorf("invalid options document: %w", err)
+		}
+		if document.Options == nil {
+			return nil, nil, fmt.Errorf("invalid options document: missing options")
+		}
+
+		for key, raw := range document.Options {
+			value, err := json.Marshal(raw)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid value of option %s: %w", key, err)
+			}
+			values[key] = string(value)
+		}
+		secrets = document.Secrets
+	default:
+		return nil, nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	for key := range values {
+		if !IsValidKey(key) {
+			return nil, nil, fmt.Errorf("invalid option key: %q", key)
+		}
+	}
+
+	for _, key := range secrets {
+		if _, ok := values[key]; !ok {
+			return nil, nil, fmt.Errorf("invalid options document: secret option %s is missing", key)
+		}
+	}
+
+	return values, secrets, nil
+}
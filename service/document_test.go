@@ -0,0 +1,72 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitFormatFromPath(t *testing.T) {
+	assert.Equal(t, FormatYAML, FormatFromPath("options.yml"))
+	assert.Equal(t, FormatYAML, FormatFromPath("/tmp/options.YAML"))
+	assert.Equal(t, FormatJSON, FormatFromPath("options.json"))
+	assert.Equal(t, FormatJSON, FormatFromPath("options"))
+}
+
+func TestUnitEncodeDecodeOptions(t *testing.T) {
+	values := map[string]string{
+		"app.name":  `"zewi"`,
+		"app.limit": `12345678901234567890`,
+		"app.db":    `{"host":"localhost","ports":[5432,5433],"tls":false}`,
+		"app.none":  `null`,
+	}
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run("Round trip "+format, func(t *testing.T) {
+			data, err := EncodeOptions(format, "staging", values, []string{"app.name", "app.db"})
+			assert.NoError(t, err)
+
+			decoded, secrets, err := DecodeOptions(format, data)
+			assert.NoError(t, err)
+			assert.Len(t, decoded, len(values))
+			for key, value := range values {
+				assert.JSONEq(t, value, decoded[key], key)
+			}
+			assert.Equal(t, []string{"app.db", "app.name"}, secrets)
+		})
+	}
+
+	t.Run("YAML is written in block style", func(t *testing.T) {
+		data, err := EncodeOptions(FormatYAML, "staging", map[string]string{"app": `{"name":"zewi","tags":["a"]}`}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "namespace: staging\noptions:\n  app:\n    name: zewi\n    tags:\n      - a\n", string(data))
+	})
+
+	t.Run("Decodes hand written YAML", func(t *testing.T) {
+		decoded, secrets, err := DecodeOptions(FormatYAML, []byte("options:\n  state: on-call\n  retries: 3\nsecrets:\n  - state\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"state": `"on-call"`, "retries": `3`}, decoded)
+		assert.Equal(t, []string{"state"}, secrets)
+	})
+
+	t.Run("Rejects invalid documents", func(t *testing.T) {
+		_, _, err := DecodeOptions(FormatJSON, []byte(`{"namespace":"x"}`))
+		assert.Error(t, err)
+
+		_, _, err = DecodeOptions(FormatJSON, []byte(`{"options":{"bad key":1}}`))
+		assert.Error(t, err)
+
+		_, _, err = DecodeOptions(FormatJSON, []byte(`{"options":{"a":1},"secrets":["b"]}`))
+		assert.Error(t, err)
+
+		_, _, err = DecodeOptions(FormatYAML, []byte("options: [1, 2]"))
+		assert.Error(t, err)
+
+		_, _, err = DecodeOptions("xml", []byte(`<options/>`))
+		assert.Error(t, err)
+	})
+}
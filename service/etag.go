@@ -0,0 +1,32 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ETag builds a strong entity tag from a version number
+func ETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ParseETag extracts the version number from a strong entity tag
+func ParseETag(tag string) (int64, bool) {
+	tag = strings.TrimSpace(tag)
+
+	if len(tag) < 3 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+
+	return version, true
+}
@@ -0,0 +1,32 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitETag(t *testing.T) {
+	t.Run("ETag round trip", func(t *testing.T) {
+		version, ok := ParseETag(ETag(42))
+		assert.True(t, ok)
+		assert.Equal(t, int64(42), version)
+	})
+
+	t.Run("ParseETag tolerates surrounding spaces", func(t *testing.T) {
+		version, ok := ParseETag(` "7" `)
+		assert.True(t, ok)
+		assert.Equal(t, int64(7), version)
+	})
+
+	t.Run("ParseETag rejects malformed tags", func(t *testing.T) {
+		for _, tag := range []string{"", "7", `""`, `"abc"`, `W/"7"`, `"0"`, `"-1"`, "*"} {
+			_, ok := ParseETag(tag)
+			assert.False(t, ok, tag)
+		}
+	})
+}
@@ -0,0 +1,451 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Flag types
+const (
+	// FlagTypeBoolean flags serve the on or off variant
+	FlagTypeBoolean = "boolean"
+	// FlagTypePercentage flags serve the on variant to a percentage of users
+	FlagTypePercentage = "percentage"
+	// FlagTypeVariant flags serve one of their own variants
+	FlagTypeVariant = "variant"
+)
+
+// Variants of boolean and percentage flags
+const (
+	FlagVariantOn  = "on"
+	FlagVariantOff = "off"
+)
+
+// Reasons a variant was served
+const (
+	// FlagReasonDisabled means the flag is disabled and served its default
+	FlagReasonDisabled = "disabled"
+	// FlagReasonRuleMatch means a targeting rule matched the context
+	FlagReasonRuleMatch = "rule_match"
+	// FlagReasonRollout means the percentage rollout of the flag was applied
+	FlagReasonRollout = "rollout"
+	// FlagReasonDefault means no rule matched and the default was served
+	FlagReasonDefault = "default"
+	// FlagReasonNotFound means the flag does not exist
+	FlagReasonNotFound = "not_found"
+	// FlagReasonError means the flag definition could not be evaluated
+	FlagReasonError = "error"
+)
+
+// Condition operators
+const (
+	FlagOperatorEq         = "eq"
+	FlagOperatorNeq        = "neq"
+	FlagOperatorIn         = "in"
+	FlagOperatorNotIn      = "not_in"
+	FlagOperatorContains   = "contains"
+	FlagOperatorStartsWith = "starts_with"
+	FlagOperatorEndsWith   = "ends_with"
+	FlagOperatorGt         = "gt"
+	FlagOperatorGte        = "gte"
+	FlagOperatorLt         = "lt"
+	FlagOperatorLte        = "lte"
+	FlagOperatorExists     = "exists"
+)
+
+// FlagAttributeUserID is the condition attribute matching the user ID of the
+// context
+const FlagAttributeUserID = "user_id"
+
+// flagBuckets is the number of buckets users are hashed into, so rollouts have
+// a precision of 0.01%
+const flagBuckets = 10000
+
+// Flag is a feature flag definition, stored as the JSON value of an option
+type Flag struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+	// Variants are the values served by variant flags by name. Boolean and
+	// percentage flags have the on (true) and off (false) variants.
+	Variants map[string]json.RawMessage `json:"variants,omitempty"`
+	// Default is the variant served when the flag is disabled or no rule
+	// matches, off for boolean and percentage flags unless set
+	Default string `json:"default,omitempty"`
+	// Percentage is the share of users percentage flags serve on to when no
+	// rule matches
+	Percentage float64 `json:"percentage,omitempty"`
+	// Rules are evaluated in order, the first matching rule wins
+	Rules []FlagRule `json:"rules,omitempty"`
+}
+
+// FlagRule serves a variant, or splits users between variants, when all of its
+// conditions match the context
+type FlagRule struct {
+	Conditions []FlagCondition `json:"conditions"`
+	Variant    string          `json:"variant,omitempty"`
+	Rollout    []FlagRollout   `json:"rollout,omitempty"`
+}
+
+// FlagRollout is the percentage of users served a variant
+type FlagRollout struct {
+	Variant string  `json:"variant"`
+	Weight  float64 `json:"weight"`
+}
+
+// FlagCondition compares an attribute of the context with values. Missing
+// attributes never match, except for the exists operator.
+type FlagCondition struct {
+	Attribute string        `json:"attribute"`
+	Operator  string        `json:"operator"`
+	Values    []interface{} `json:"values,omitempty"`
+}
+
+// FlagContext is what flags are evaluated against. Rollouts hash the user ID,
+// so a user keeps being served the same variant.
+type FlagContext struct {
+	UserID     string                 `json:"user_id"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// FlagResult is the variant a flag resolved to
+type FlagResult struct {
+	Variant string          `json:"variant"`
+	Value   json.RawMessage `json:"value"`
+	Reason  string          `json:"reason"`
+	// Rule is the index of the matching rule, if any
+	Rule *int `json:"rule,omitempty"`
+}
+
+// ParseFlag decodes a flag definition and returns every violation found if it
+// is not valid
+func ParseFlag(document string) (*Flag, []SchemaViolation) {
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.DisallowUnknownFields()
+
+	var flag Flag
+	if err := decoder.Decode(&flag); err != nil {
+		return nil, []SchemaViolation{{Message: fmt.Sprintf("invalid flag definition: %s", err)}}
+	}
+
+	if flag.Default == "" && flag.Type != FlagTypeVariant {
+		flag.Default = FlagVariantOff
+	}
+
+	violations := flag.validate()
+	if len(violations) > 0 {
+		return nil, violations
+	}
+	return &flag, nil
+}
+
+// validate checks the flag definition is consistent
+func (f *Flag) validate() []SchemaViolation {
+	var violations []SchemaViolation
+	add := func(path, format string, args ...interface{}) {
+		violations = append(violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch f.Type {
+	case FlagTypeBoolean, FlagTypePercentage:
+		if len(f.Variants) > 0 {
+			add("/variants", "%s flags have the fixed variants on and off", f.Type)
+		}
+	case FlagTypeVariant:
+		if len(f.Variants) == 0 {
+			add("/variants", "variant flags need at least one variant")
+		}
+		for name, value := range f.Variants {
+			if !IsValidKey(name) {
+				add("/variants/"+name, "invalid variant name")
+			}
+			if !json.Valid(value) {
+				add("/variants/"+name, "variant value must be a JSON document")
+			}
+		}
+	default:
+		add("/type", "type must be one of %s, %s or %s", FlagTypeBoolean, FlagTypePercentage, FlagTypeVariant)
+		return violations
+	}
+
+	if f.Default == "" {
+		add("/default", "default variant is required")
+	} else if !f.hasVariant(f.Default) {
+		add("/default", "unknown variant %s", f.Default)
+	}
+
+	if f.Type == FlagTypePercentage {
+		if f.Percentage < 0 || f.Percentage > 100 {
+			add("/percentage", "percentage must be between 0 and 100")
+		}
+	} else if f.Percentage != 0 {
+		add("/percentage", "only percentage flags have a percentage")
+	}
+
+	for i, rule := range f.Rules {
+		path := fmt.Sprintf("/rules/%d", i)
+
+		if len(rule.Conditions) == 0 {
+			add(path+"/conditions", "rules need at least one condition")
+		}
+		for j, condition := range rule.Conditions {
+			if message := condition.validate(); message != "" {
+				add(fmt.Sprintf("%s/conditions/%d", path, j), "%s", message)
+			}
+		}
+
+		switch {
+		case rule.Variant != "" && len(rule.Rollout) > 0:
+			add(path, "rules serve either a variant or a rollout")
+		case rule.Variant != "":
+			if !f.hasVariant(rule.Variant) {
+				add(path+"/variant", "unknown variant %s", rule.Variant)
+			}
+		case len(rule.Rollout) > 0:
+			total := 0.0
+			for j, entry := range rule.Rollout {
+				if !f.hasVariant(entry.Variant) {
+					add(fmt.Sprintf("%s/rollout/%d/variant", path, j), "unknown variant %s", entry.Variant)
+				}
+				if entry.Weight < 0 {
+					add(fmt.Sprintf("%s/rollout/%d/weight", path, j), "weight must not be negative")
+				}
+				total += entry.Weight
+			}
+			if math.Abs(total-100) > 1e-9 {
+				add(path+"/rollout", "weights must add up to 100")
+			}
+		default:
+			add(path, "rules serve a variant or a rollout")
+		}
+	}
+
+	return violations
+}
+
+// validate returns why the condition is invalid, or an empty string
+func (c *FlagCondition) validate() string {
+	if c.Attribute == "" {
+		return "attribute is required"
+	}
+
+	switch c.Operator {
+	case FlagOperatorExists:
+		return ""
+	case FlagOperatorEq, FlagOperatorNeq, FlagOperatorGt, FlagOperatorGte, FlagOperatorLt, FlagOperatorLte:
+		if len(c.Values) != 1 {
+			return fmt.Sprintf("operator %s takes one value", c.Operator)
+		}
+	case FlagOperatorIn, FlagOperatorNotIn, FlagOperatorContains, FlagOperatorStartsWith, FlagOperatorEndsWith:
+		if len(c.Values) == 0 {
+			return fmt.Sprintf("operator %s takes at least one value", c.Operator)
+		}
+	default:
+		return fmt.Sprintf("unknown operator %s", c.Operator)
+	}
+
+	for _, value := range c.Values {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return "values must be strings, numbers or booleans"
+		}
+	}
+
+	switch c.Operator {
+	case FlagOperatorGt, FlagOperatorGte, FlagOperatorLt, FlagOperatorLte:
+		if _, ok := c.Values[0].(float64); !ok {
+			return fmt.Sprintf("operator %s takes a number", c.Operator)
+		}
+	}
+	return ""
+}
+
+// hasVariant reports whether the flag serves the given variant
+func (f *Flag) hasVariant(name string) bool {
+	if f.Type != FlagTypeVariant {
+		return name == FlagVariantOn || name == FlagVariantOff
+	}
+	_, ok := f.Variants[name]
+	return ok
+}
+
+// value returns the JSON value of a variant
+func (f *Flag) value(name string) json.RawMessage {
+	if f.Type != FlagTypeVariant {
+		return json.RawMessage(strconv.FormatBool(name == FlagVariantOn))
+	}
+	return f.Variants[name]
+}
+
+// Evaluate resolves the variant of the flag with the given key for a context.
+// The key salts the hash of the user ID, so rollouts of different flags are
+// independent.
+func (f *Flag) Evaluate(key string, context FlagContext) FlagResult {
+	if !f.Enabled {
+		return f.result(f.Default, FlagReasonDisabled, nil)
+	}
+
+	for i, rule := range f.Rules {
+		if !rule.matches(context) {
+			continue
+		}
+
+		variant := rule.Variant
+		if len(rule.Rollout) > 0 {
+			// Without a user ID the rollout can not be applied, the
+			// default is served instead
+			var ok bool
+			if variant, ok = rollout(key, context.UserID, rule.Rollout); !ok {
+				break
+			}
+		}
+
+		index := i
+		return f.result(variant, FlagReasonRuleMatch, &index)
+	}
+
+	if f.Type == FlagTypePercentage {
+		variant, ok := rollout(key, context.UserID, []FlagRollout{
+			{Variant: FlagVariantOn, Weight: f.Percentage},
+			{Variant: FlagVariantOff, Weight: 100 - f.Percentage},
+		})
+		if ok {
+			return f.result(variant, FlagReasonRollout, nil)
+		}
+	}
+
+	return f.result(f.Default, FlagReasonDefault, nil)
+}
+
+// result builds the result serving a variant
+func (f *Flag) result(variant, reason string, rule *int) FlagResult {
+	return FlagResult{
+		Variant: variant,
+		Value:   f.value(variant),
+		Reason:  reason,
+		Rule:    rule,
+	}
+}
+
+// matches reports whether every condition of the rule matches the context
+func (r *FlagRule) matches(context FlagContext) bool {
+	for _, condition := range r.Conditions {
+		if !condition.matches(context) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the condition matches the context
+func (c *FlagCondition) matches(context FlagContext) bool {
+	var attribute interface{}
+	if c.Attribute == FlagAttributeUserID {
+		if context.UserID != "" {
+			attribute = context.UserID
+		}
+	} else {
+		attribute = context.Attributes[c.Attribute]
+	}
+
+	if attribute == nil {
+		return false
+	}
+
+	switch c.Operator {
+	case FlagOperatorExists:
+		return true
+	case FlagOperatorEq, FlagOperatorIn:
+		return c.any(attribute, func(a, v string) bool { return a == v })
+	case FlagOperatorNeq, FlagOperatorNotIn:
+		return !c.any(attribute, func(a, v string) bool { return a == v })
+	case FlagOperatorContains:
+		return c.any(attribute, strings.Contains)
+	case FlagOperatorStartsWith:
+		return c.any(attribute, strings.HasPrefix)
+	case FlagOperatorEndsWith:
+		return c.any(attribute, strings.HasSuffix)
+	}
+
+	number, ok := attribute.(float64)
+	if !ok {
+		var err error
+		if number, err = strconv.ParseFloat(formatFlagValue(attribute), 64); err != nil {
+			return false
+		}
+	}
+	value, _ := c.Values[0].(float64)
+
+	switch c.Operator {
+	case FlagOperatorGt:
+		return number > value
+	case FlagOperatorGte:
+		return number >= value
+	case FlagOperatorLt:
+		return number < value
+	case FlagOperatorLte:
+		return number <= value
+	}
+	return false
+}
+
+// any reports whether the attribute compares true with any condition value
+func (c *FlagCondition) any(attribute interface{}, compare func(attribute, value string) bool) bool {
+	formatted := formatFlagValue(attribute)
+	for _, value := range c.Values {
+		if compare(formatted, formatFlagValue(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatFlagValue formats a JSON scalar for string comparisons
+func formatFlagValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// rollout picks the variant of a user by hashing the user ID into a bucket. It
+// returns false without a user ID.
+func rollout(key, userID string, rollouts []FlagRollout) (string, bool) {
+	if userID == "" || len(rollouts) == 0 {
+		return "", false
+	}
+
+	point := float64(FlagBucket(key, userID)) * 100 / flagBuckets
+
+	cumulative := 0.0
+	for _, entry := range rollouts {
+		cumulative += entry.Weight
+		if point < cumulative {
+			return entry.Variant, true
+		}
+	}
+	return rollouts[len(rollouts)-1].Variant, true
+}
+
+// FlagBucket returns the stable bucket in [0, 10000) a user falls into for the
+// flag with the given key
+func FlagBucket(key, userID string) int {
+	sum := sha256.Sum256([]byte(key + ":" + userID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % flagBuckets)
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitFlag(t *testing.T) {
+	t.Run("Serves the default of boolean flags", func(t *testing.T) {
+		flag, violations := ParseFlag(`{"type":"boolean","enabled":true}`)
+		assert.Empty(t, violations)
+
+		result := flag.Evaluate("flags.dark", FlagContext{UserID: "u1"})
+		assert.Equal(t, FlagVariantOff, result.Variant)
+		assert.Equal(t, "false", string(result.Value))
+		assert.Equal(t, FlagReasonDefault, result.Reason)
+		assert.Nil(t, result.Rule)
+	})
+
+	t.Run("Serves the default of disabled flags", func(t *testing.T) {
+		flag, violations := ParseFlag(`{
+			"type": "boolean",
+			"enabled": false,
+			"rules": [{"conditions": [{"attribute": "user_id", "operator": "exists"}], "variant": "on"}]
+		}`)
+		assert.Empty(t, violations)
+
+		result := flag.Evaluate("flags.dark", FlagContext{UserID: "u1"})
+		assert.Equal(t, FlagVariantOff, result.Variant)
+		assert.Equal(t, FlagReasonDisabled, result.Reason)
+	})
+
+	t.Run("Matches targeting rules in order", func(t *testing.T) {
+		flag, violations := ParseFlag(`{
+			"type": "variant",
+			"enabled": true,
+			"variants": {"blue": "#00f", "red": "#f00", "grey": {"hex": "#888"}},
+			"default": "grey",
+			"rules": [
+				{"conditions": [{"attribute": "country", "operator": "in", "values": ["DE", "FR"]}, {"attribute": "age", "operator": "gte", "values": [18]}], "variant": "blue"},
+				{"conditions": [{"attribute": "email", "operator": "ends_with", "values": ["@example.com"]}], "variant": "red"}
+			]
+		}`)
+		assert.Empty(t, violations)
+
+		result := flag.Evaluate("flags.color", FlagContext{Attributes: map[string]interface{}{"country": "DE", "age": float64(30)}})
+		assert.Equal(t, "blue", result.Variant)
+		assert.Equal(t, `"#00f"`, string(result.Value))
+		assert.Equal(t, FlagReasonRuleMatch, result.Reason)
+		assert.Equal(t, 0, *result.Rule)
+
+		result = flag.Evaluate("flags.color", FlagContext{Attributes: map[string]interface{}{"country": "DE", "age": float64(16), "email": "a@example.com"}})
+		assert.Equal(t, "red", result.Variant)
+		assert.Equal(t, 1, *result.Rule)
+
+		result = flag.Evaluate("flags.color", FlagContext{})
+		assert.Equal(t, "grey", result.Variant)
+		assert.Equal(t, FlagReasonDefault, result.Reason)
+	})
+
+	t.Run("Rolls out percentage flags by user", func(t *testing.T) {
+		flag, violations := ParseFlag(`{"type":"percentage","enabled":true,"percentage":25}`)
+		assert.Empty(t, violations)
+
+		on := 0
+		for i := 0; i < 4000; i++ {
+			userID := fmt.Sprintf("user-%d", i)
+
+			result := flag.Evaluate("flags.beta", FlagContext{UserID: userID})
+			assert.Equal(t, FlagReasonRollout, result.Reason)
+			assert.Equal(t, result, flag.Evaluate("flags.beta", FlagContext{UserID: userID}))
+
+			if result.Variant == FlagVariantOn {
+				on++
+			}
+		}
+		assert.InDelta(t, 1000, on, 150)
+
+		result := flag.Evaluate("flags.beta", FlagContext{})
+		assert.Equal(t, FlagVariantOff, result.Variant)
+		assert.Equal(t, FlagReasonDefault, result.Reason)
+	})
+
+	t.Run("Splits rule matches between variants", func(t *testing.T) {
+		flag, violations := ParseFlag(`{
+			"type": "variant",
+			"enabled": true,
+			"variants": {"a": "1", "b": "2"},
+			"default": "a",
+			"rules": [{"conditions": [{"attribute": "plan", "operator": "eq", "values": ["pro"]}], "rollout": [{"variant": "a", "weight": 0}, {"variant": "b", "weight": 100}]}]
+		}`)
+		assert.Empty(t, violations)
+
+		result := flag.Evaluate("flags.split", FlagContext{UserID: "u1", Attributes: map[string]interface{}{"plan": "pro"}})
+		assert.Equal(t, "b", result.Variant)
+		assert.Equal(t, FlagReasonRuleMatch, result.Reason)
+	})
+
+	t.Run("Hashes users into stable buckets", func(t *testing.T) {
+		assert.Equal(t, FlagBucket("flags.a", "u1"), FlagBucket("flags.a", "u1"))
+		assert.True(t, FlagBucket("flags.a", "u1") >= 0 && FlagBucket("flags.a", "u1") < 10000)
+	})
+
+	t.Run("Rejects invalid flags", func(t *testing.T) {
+		for _, document := range []string{
+			`[]`,
+			`{"type":"toggle","enabled":true}`,
+			`{"type":"boolean","enabled":true,"unknown":1}`,
+			`{"type":"boolean","enabled":true,"variants":{"x":"1"}}`,
+			`{"type":"boolean","enabled":true,"percentage":10}`,
+			`{"type":"percentage","enabled":true,"percentage":120}`,
+			`{"type":"variant","enabled":true,"variants":{"a":"1"}}`,
+			`{"type":"variant","enabled":true,"variants":{"a":"1"},"default":"b"}`,
+			`{"type":"boolean","enabled":true,"rules":[{"conditions":[],"variant":"on"}]}`,
+			`{"type":"boolean","enabled":true,"rules":[{"conditions":[{"attribute":"a","operator":"like","values":["x"]}],"variant":"on"}]}`,
+			`{"type":"boolean","enabled":true,"rules":[{"conditions":[{"attribute":"a","operator":"gt","values":["x"]}],"variant":"on"}]}`,
+			`{"type":"boolean","enabled":true,"rules":[{"conditions":[{"attribute":"a","operator":"exists"}]}]}`,
+			`{"type":"boolean","enabled":true,"rules":[{"conditions":[{"attribute":"a","operator":"exists"}],"rollout":[{"variant":"on","weight":60}]}]}`,
+		} {
+			flag, violations := ParseFlag(document)
+			assert.Nil(t, flag, document)
+			assert.NotEmpty(t, violations, document)
+		}
+	})
+}
@@ -5,6 +5,8 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -28,3 +30,9 @@ func DecodeJSON(r *http.Request, v interface{}) error {
 	}
 	return nil
 }
+
+// HashValue returns the hex encoded SHA-256 digest of a value
+func HashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
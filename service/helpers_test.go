@@ -132,3 +132,11 @@ func TestUnitWriteJSON(t *testing.T) {
 		assert.Equal(t, "John Doe", user["name"])
 	})
 }
+
+func TestUnitHashValue(t *testing.T) {
+	t.Run("Hashes with SHA-256", func(t *testing.T) {
+		assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", HashValue(""))
+		assert.Equal(t, HashValue(`{"a":1}`), HashValue(`{"a":1}`))
+		assert.NotEqual(t, HashValue(`{"a":1}`), HashValue(`{"a":2}`))
+	})
+}
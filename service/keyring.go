@@ -0,0 +1,197 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// keySize is the size in bytes of key encryption keys and data keys (AES-256)
+const keySize = 32
+
+// Keyring holds the key encryption keys used for envelope encryption by ID.
+// Every value is encrypted with its own random data key, which is in turn
+// wrapped by the active key encryption key. The other keys are only used to
+// unwrap data keys until they are rotated.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewKeyring creates a keyring from 256-bit keys by ID, wrapping new data keys
+// with the active one
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", active)
+	}
+
+	keyring := &Keyring{active: active, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if !IsValidKey(id) {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+		keyring.keys[id] = key
+	}
+
+	return keyring, nil
+}
+
+// ParseKeyring parses keys given as id:base64 pairs separated by commas or
+// new lines. The first key is the active one. Empty lines and lines starting
+// with # are ignored.
+func ParseKeyring(spec string) (*Keyring, error) {
+	active := ""
+	keys := map[string][]byte{}
+
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid key entry, expected id:base64")
+		}
+
+		id = strings.TrimSpace(id)
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 for key %q", id)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+
+		keys[id] = key
+		if active == "" {
+			active = id
+		}
+	}
+
+	if active == "" {
+		return nil, fmt.Errorf("no keys given")
+	}
+
+	return NewKeyring(active, keys)
+}
+
+// ActiveKeyID returns the ID of the key wrapping new data keys
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Seal encrypts a value with a new data key bound to the additional data, and
+// returns the ID of the key wrapping the data key, the wrapped data key and
+// the ciphertext, all base64 encoded
+func (k *Keyring) Seal(plaintext, additionalData []byte) (keyID, dataKey, ciphertext string, err error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", "", "", err
+	}
+
+	sealed, err := seal(key, plaintext, additionalData)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	wrapped, err := seal(k.keys[k.active], key, []byte(k.active))
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return k.active, wrapped, sealed, nil
+}
+
+// Open decrypts a value sealed by Seal with the same additional data
+func (k *Keyring) Open(keyID, dataKey, ciphertext string, additionalData []byte) ([]byte, error) {
+	key, err := k.unwrap(keyID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(key, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap unwraps a data key and wraps it again with the active key. The
+// ciphertext sealed with the data key stays valid.
+func (k *Keyring) Rewrap(keyID, dataKey string) (string, string, error) {
+	key, err := k.unwrap(keyID, dataKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	wrapped, err := seal(k.keys[k.active], key, []byte(k.active))
+	if err != nil {
+		return "", "", err
+	}
+	return k.active, wrapped, nil
+}
+
+// unwrap decrypts a data key wrapped by the given key encryption key
+func (k *Keyring) unwrap(keyID, dataKey string) ([]byte, error) {
+	wrapping, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q is not in the keyring", keyID)
+	}
+
+	key, err := open(wrapping, dataKey, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return key, nil
+}
+
+// seal encrypts plaintext with AES-GCM and returns the base64 encoded nonce
+// followed by the ciphertext
+func seal(key, plaintext, additionalData []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, additionalData)), nil
+}
+
+// open decrypts the output of seal
+func open(key []byte, encoded string, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("malformed ciphertext")
+	}
+
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+}
+
+// newAEAD creates an AES-GCM cipher
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
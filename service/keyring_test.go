@@ -0,0 +1,76 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestUnitKeyring(t *testing.T) {
+	t.Run("Seals and opens values", func(t *testing.T) {
+		keyring, err := ParseKeyring("k1:" + testKey(1))
+		assert.NoError(t, err)
+
+		keyID, dataKey, ciphertext, err := keyring.Seal([]byte(`"secret"`), []byte("default/db.password"))
+		assert.NoError(t, err)
+		assert.Equal(t, "k1", keyID)
+		assert.NotContains(t, ciphertext, "secret")
+
+		plaintext, err := keyring.Open(keyID, dataKey, ciphertext, []byte("default/db.password"))
+		assert.NoError(t, err)
+		assert.Equal(t, `"secret"`, string(plaintext))
+
+		_, err = keyring.Open(keyID, dataKey, ciphertext, []byte("default/other"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Rewraps data keys with the active key", func(t *testing.T) {
+		old, err := ParseKeyring("k1:" + testKey(1))
+		assert.NoError(t, err)
+
+		keyID, dataKey, ciphertext, err := old.Seal([]byte("value"), nil)
+		assert.NoError(t, err)
+
+		keyring, err := ParseKeyring("# rotated\nk2:" + testKey(2) + "\nk1:" + testKey(1) + "\n")
+		assert.NoError(t, err)
+		assert.Equal(t, "k2", keyring.ActiveKeyID())
+
+		keyID, dataKey, err = keyring.Rewrap(keyID, dataKey)
+		assert.NoError(t, err)
+		assert.Equal(t, "k2", keyID)
+
+		rotated, err := ParseKeyring("k2:" + testKey(2))
+		assert.NoError(t, err)
+
+		plaintext, err := rotated.Open(keyID, dataKey, ciphertext, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "value", string(plaintext))
+
+		_, err = old.Open(keyID, dataKey, ciphertext, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects invalid keys", func(t *testing.T) {
+		for _, spec := range []string{
+			"",
+			"k1",
+			"k1:not-base64!",
+			"k1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+			"k1:" + testKey(1) + ",k1:" + testKey(2),
+			"bad id:" + testKey(1),
+		} {
+			_, err := ParseKeyring(spec)
+			assert.Error(t, err, spec)
+		}
+	})
+}
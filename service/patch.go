@@ -0,0 +1,73 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	// MergePatchContentType is the media type of a JSON Merge Patch (RFC 7396)
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType is the media type of a JSON Patch (RFC 6902)
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+// ErrUnsupportedPatch is returned when the patch media type is not supported
+var ErrUnsupportedPatch = errors.New("unsupported patch content type")
+
+// NormalizeJSON validates a JSON document and returns its compact form
+func NormalizeJSON(document []byte) (string, error) {
+	if !json.Valid(document) {
+		return "", fmt.Errorf("invalid JSON document")
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, document); err != nil {
+		return "", fmt.Errorf("invalid JSON document: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// ApplyPatch applies a JSON Merge Patch or a JSON Patch to a document,
+// depending on the given content type, and returns the compacted result
+func ApplyPatch(contentType string, document, patch []byte) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrUnsupportedPatch
+	}
+
+	var result []byte
+
+	switch mediaType {
+	case MergePatchContentType:
+		if !json.Valid(patch) {
+			return "", fmt.Errorf("invalid merge patch document")
+		}
+		result, err = jsonpatch.MergePatch(document, patch)
+	case JSONPatchContentType:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return "", fmt.Errorf("invalid JSON patch document: %w", err)
+		}
+		result, err = operations.Apply(document)
+	default:
+		return "", ErrUnsupportedPatch
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to apply patch: %w", err)
+	}
+
+	return NormalizeJSON(result)
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitNormalizeJSON(t *testing.T) {
+	t.Run("Compacts valid documents", func(t *testing.T) {
+		result, err := NormalizeJSON([]byte("{ \"a\" : [1, 2],\n \"b\": \"x\" }"))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a":[1,2],"b":"x"}`, result)
+	})
+
+	t.Run("Rejects invalid documents", func(t *testing.T) {
+		for _, doc := range []string{"", "{", "plain text", `{"a":}`} {
+			_, err := NormalizeJSON([]byte(doc))
+			assert.Error(t, err, doc)
+		}
+	})
+}
+
+func TestUnitApplyPatch(t *testing.T) {
+	document := []byte(`{"name":"zewi","tags":["a"],"limits":{"cpu":1,"memory":2}}`)
+
+	t.Run("Merge patch", func(t *testing.T) {
+		result, err := ApplyPatch(
+			MergePatchContentType,
+			document,
+			[]byte(`{"name":"rat","limits":{"memory":null}}`),
+		)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"name":"rat","tags":["a"],"limits":{"cpu":1}}`, result)
+	})
+
+	t.Run("Merge patch with charset parameter", func(t *testing.T) {
+		result, err := ApplyPatch(MergePatchContentType+"; charset=utf-8", document, []byte(`{"name":"x"}`))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"name":"x","tags":["a"],"limits":{"cpu":1,"memory":2}}`, result)
+	})
+
+	t.Run("JSON patch", func(t *testing.T) {
+		result, err := ApplyPatch(
+			JSONPatchContentType,
+			document,
+			[]byte(`[{"op":"add","path":"/tags/-","value":"b"},{"op":"replace","path":"/limits/cpu","value":4}]`),
+		)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"name":"zewi","tags":["a","b"],"limits":{"cpu":4,"memory":2}}`, result)
+	})
+
+	t.Run("JSON patch with failing test operation", func(t *testing.T) {
+		_, err := ApplyPatch(
+			JSONPatchContentType,
+			document,
+			[]byte(`[{"op":"test","path":"/name","value":"other"}]`),
+		)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid patch documents", func(t *testing.T) {
+		_, err := ApplyPatch(JSONPatchContentType, document, []byte(`{"op":"add"}`))
+		assert.Error(t, err)
+
+		_, err = ApplyPatch(MergePatchContentType, document, []byte(`{`))
+		assert.Error(t, err)
+	})
+
+	t.Run("Unsupported content type", func(t *testing.T) {
+		_, err := ApplyPatch("application/json", document, []byte(`{}`))
+		assert.ErrorIs(t, err, ErrUnsupportedPatch)
+
+		_, err = ApplyPatch("", document, []byte(`{}`))
+		assert.ErrorIs(t, err, ErrUnsupportedPatch)
+	})
+}
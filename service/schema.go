@@ -0,0 +1,90 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaURL is the resource name schemas are compiled under
+const schemaURL = "mem://zewi/schema.json"
+
+// SchemaViolation describes a part of a JSON document that does not conform to a schema
+type SchemaViolation struct {
+	// Path is the JSON Pointer of the offending value, empty for the document itself
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// CompileSchema compiles a JSON Schema document. References to external
+// documents are not resolved.
+func CompileSchema(schema string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("loading external schema %s is not allowed", url)
+	}
+
+	if err := compiler.AddResource(schemaURL, strings.NewReader(schema)); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	return compiled, nil
+}
+
+// ValidateSchema validates a JSON document against a JSON Schema and returns
+// every violation found. An empty result means the document conforms.
+func ValidateSchema(schema, document string) ([]SchemaViolation, error) {
+	compiled, err := CompileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+
+	err = compiled.Validate(value)
+	if err == nil {
+		return nil, nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	return collectViolations(validationErr, nil), nil
+}
+
+// collectViolations flattens a validation error tree into its leaf violations
+func collectViolations(err *jsonschema.ValidationError, violations []SchemaViolation) []SchemaViolation {
+	if len(err.Causes) == 0 {
+		return append(violations, SchemaViolation{
+			Path:    err.InstanceLocation,
+			Message: err.Message,
+		})
+	}
+
+	for _, cause := range err.Causes {
+		violations = collectViolations(cause, violations)
+	}
+
+	return violations
+}
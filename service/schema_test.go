@@ -0,0 +1,71 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitCompileSchema(t *testing.T) {
+	t.Run("Compiles valid schemas", func(t *testing.T) {
+		_, err := CompileSchema(`{"type":"object","properties":{"port":{"type":"integer"}}}`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Rejects invalid schemas", func(t *testing.T) {
+		for _, schema := range []string{"", "{", `{"type":"nothing"}`, `{"minimum":"one"}`} {
+			_, err := CompileSchema(schema)
+			assert.Error(t, err, schema)
+		}
+	})
+
+	t.Run("Refuses external references", func(t *testing.T) {
+		_, err := CompileSchema(`{"$ref":"file:///etc/passwd"}`)
+		assert.Error(t, err)
+	})
+}
+
+func TestUnitValidateSchema(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["host"],
+		"properties": {
+			"host": {"type": "string"},
+			"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+		}
+	}`
+
+	t.Run("Conforming document", func(t *testing.T) {
+		violations, err := ValidateSchema(schema, `{"host":"localhost","port":8080}`)
+		assert.NoError(t, err)
+		assert.Empty(t, violations)
+	})
+
+	t.Run("Reports each violation path", func(t *testing.T) {
+		violations, err := ValidateSchema(schema, `{"port":0}`)
+		assert.NoError(t, err)
+		assert.Len(t, violations, 2)
+
+		paths := []string{}
+		for _, violation := range violations {
+			paths = append(paths, violation.Path)
+			assert.NotEmpty(t, violation.Message)
+		}
+		assert.ElementsMatch(t, []string{"", "/port"}, paths)
+	})
+
+	t.Run("Wrong document type", func(t *testing.T) {
+		violations, err := ValidateSchema(schema, `"text"`)
+		assert.NoError(t, err)
+		assert.Equal(t, []SchemaViolation{{Path: "", Message: "expected object, but got string"}}, violations)
+	})
+
+	t.Run("Invalid schema", func(t *testing.T) {
+		_, err := ValidateSchema("{", `{}`)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,24 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"regexp"
+)
+
+// MaxKeyLength is the maximum allowed length of an option key
+const MaxKeyLength = 255
+
+// keyPattern restricts keys to characters that are safe in URL path segments
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]*$`)
+
+// IsValidKey reports whether the key can be used as an option key
+func IsValidKey(key string) bool {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return false
+	}
+
+	return keyPattern.MatchString(key)
+}
@@ -0,0 +1,31 @@
+// Copyright 2025 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitIsValidKey(t *testing.T) {
+	t.Run("Valid keys", func(t *testing.T) {
+		for _, key := range []string{"state", "a", "app.feature-x", "db:timeout_ms", "9lives"} {
+			assert.True(t, IsValidKey(key), key)
+		}
+	})
+
+	t.Run("Invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", ".hidden", "-flag", "a/b", "with space", "ünïcode", "a?b"} {
+			assert.False(t, IsValidKey(key), key)
+		}
+	})
+
+	t.Run("Key length limit", func(t *testing.T) {
+		assert.True(t, IsValidKey(strings.Repeat("k", MaxKeyLength)))
+		assert.False(t, IsValidKey(strings.Repeat("k", MaxKeyLength+1)))
+	})
+}
@@ -57,3 +57,124 @@ export async function updateState(value) {
     }
   }
 }
+
+/**
+ * Subscribe to state changes pushed by the server. The browser reconnects
+ * on its own and resumes from the last received change.
+ * @param {(change: {revision: number, action: string, value: *}) => void} onChange - Called for every change
+ * @returns {() => void} Function closing the subscription
+ */
+export function subscribeState(onChange) {
+  const source = new EventSource(`${API_BASE_URL}/api/v1/state/stream`)
+
+  source.addEventListener('change', (event) => {
+    onChange(JSON.parse(event.data))
+  })
+
+  return () => source.close()
+}
+
+/**
+ * Convert a failed request into an Error carrying the server message
+ * @param {*} error - The axios error
+ * @param {string} fallback - Message used when nothing better is known
+ * @returns {Error}
+ */
+function requestError(error, fallback) {
+  if (error.response) {
+    // Server responded with error status
+    return new Error(error.response.data?.error || `Server error: ${error.response.status}`)
+  } else if (error.request) {
+    // Request made but no response received
+    return new Error('No response from server. Please check your connection.')
+  }
+  // Something else happened
+  return new Error(error.message || fallback)
+}
+
+/**
+ * Base path of the flag endpoints of a namespace
+ * @param {string} [namespace] - The namespace, the default one if omitted
+ * @returns {string}
+ */
+function flagsPath(namespace) {
+  return namespace
+    ? `/api/v1/namespaces/${encodeURIComponent(namespace)}/flags`
+    : '/api/v1/flags'
+}
+
+/**
+ * List the feature flags of a namespace
+ * @param {string} [namespace] - The namespace, the default one if omitted
+ * @returns {Promise<{namespace: string, flags: Array<{name: string, flag: object, version: number}>}>}
+ */
+export async function listFlags(namespace) {
+  try {
+    const response = await apiClient.get(flagsPath(namespace))
+    return response.data
+  } catch (error) {
+    throw requestError(error, 'Failed to fetch flags')
+  }
+}
+
+/**
+ * Get a feature flag
+ * @param {string} name - The flag name
+ * @param {string} [namespace] - The namespace, the default one if omitted
+ * @returns {Promise<{name: string, flag: object, version: number}>}
+ */
+export async function getFlag(name, namespace) {
+  try {
+    const response = await apiClient.get(`${flagsPath(namespace)}/${encodeURIComponent(name)}`)
+    return response.data
+  } catch (error) {
+    throw requestError(error, 'Failed to fetch flag')
+  }
+}
+
+/**
+ * Create or update a feature flag. Invalid definitions are rejected with the
+ * violations found.
+ * @param {string} name - The flag name
+ * @param {object} flag - The flag definition
+ * @param {string} [namespace] - The namespace, the default one if omitted
+ * @returns {Promise<{name: string, flag: object, version: number}>}
+ */
+export async function saveFlag(name, flag, namespace) {
+  try {
+    const response = await apiClient.put(`${flagsPath(namespace)}/${encodeURIComponent(name)}`, flag)
+    return response.data
+  } catch (error) {
+    throw requestError(error, 'Failed to save flag')
+  }
+}
+
+/**
+ * Delete a feature flag
+ * @param {string} name - The flag name
+ * @param {string} [namespace] - The namespace, the default one if omitted
+ * @returns {Promise<void>}
+ */
+export async function deleteFlag(name, namespace) {
+  try {
+    await apiClient.delete(`${flagsPath(namespace)}/${encodeURIComponent(name)}`)
+  } catch (error) {
+    throw requestError(error, 'Failed to delete flag')
+  }
+}
+
+/**
+ * Resolve the variants of feature flags for a context
+ * @param {{user_id?: string, attributes?: object}} context - What the flags are evaluated against
+ * @param {string[]} [flags] - The flag names, all flags if omitted
+ * @param {string} [namespace] - The namespace, the default one if omitted
+ * @returns {Promise<{namespace: string, flags: Object<string, {variant: string, value: *, reason: string}>}>}
+ */
+export async function evaluateFlags(context, flags, namespace) {
+  try {
+    const response = await apiClient.post(`${flagsPath(namespace)}/evaluate`, { context, flags })
+    return response.data
+  } catch (error) {
+    throw requestError(error, 'Failed to evaluate flags')
+  }
+}